@@ -17,27 +17,84 @@ limitations under the License.
 package cmd
 
 import (
+	"os"
+
 	"github.com/labring/sealvm/pkg/actions"
+	"github.com/labring/sealvm/pkg/actions/library"
 	"github.com/spf13/cobra"
 )
 
 func newActionCmd() *cobra.Command {
 	var file string
 	var printDefault bool
+	var dryRun bool
+	var opts actions.Options
 	var actionCmd = &cobra.Command{
 		Use:  "action",
 		Args: cobra.NoArgs,
 		Example: `sealvm action -n default -f action.yaml
-sealvm action -p`,
+sealvm action -p
+sealvm action -n default -f action.yaml --dry-run
+sealvm action -n default -f action.yaml --only install-sealos
+sealvm action -n default -f action.yaml --from build
+sealvm action -n default -f action.yaml -y --report junit --report-file report.xml
+sealvm action -n default -f action.yaml --checksum`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if printDefault {
 				return actions.PrintDefault()
 			}
-			return actions.Do(name, file)
+			if dryRun {
+				return actions.Plan(name, file, opts)
+			}
+			return actions.Do(name, file, opts)
 		},
 	}
 	actionCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
 	actionCmd.Flags().StringVarP(&file, "file", "f", "", "file to apply action")
 	actionCmd.Flags().BoolVarP(&printDefault, "print-default", "p", false, "print default action")
+	actionCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the per-host plan of the action without touching any vm")
+	actionCmd.Flags().StringVar(&opts.Only, "only", "", "run only the action of the name")
+	actionCmd.Flags().StringVar(&opts.From, "from", "", "run the action of the name and all actions depending on it")
+	actionCmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "run the action without confirmation, eg: in CI")
+	actionCmd.Flags().StringVar(&opts.ReportFormat, "report", "", "write a report of every step on every host, junit or json")
+	actionCmd.Flags().StringVar(&opts.ReportFile, "report-file", "", "path of the report, default is sealvm-report.xml or sealvm-report.json")
+	actionCmd.Flags().BoolVar(&opts.Checksum, "checksum", false, "report every file of the copy steps as uploaded, skipped by checksum or deleted")
+	actionCmd.AddCommand(library.NewLibCmd())
+	actionCmd.AddCommand(newActionValidateCmd())
+	actionCmd.AddCommand(newActionSchemaCmd())
 	return actionCmd
 }
+
+func newActionValidateCmd() *cobra.Command {
+	var file string
+	var validateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "validate the action file strictly without running it",
+		Args:  cobra.NoArgs,
+		Example: `sealvm action validate -f action.yaml
+sealvm action validate -n default -f action.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return actions.Validate(name, file)
+		},
+	}
+	validateCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to validate the ons of the action against")
+	validateCmd.Flags().StringVarP(&file, "file", "f", "", "file of the action")
+	return validateCmd
+}
+
+func newActionSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "schema",
+		Short:   "print the json schema of the action files for editor completion",
+		Args:    cobra.NoArgs,
+		Example: `sealvm action schema > action.schema.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := actions.Schema()
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
+	}
+}
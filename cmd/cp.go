@@ -0,0 +1,43 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/labring/sealvm/pkg/process"
+	"github.com/spf13/cobra"
+)
+
+func newCpCmd() *cobra.Command {
+	var cpCmd = &cobra.Command{
+		Use:   "cp <local> <host>:<path> | <host>:<path> <local>",
+		Short: "Copy files and directories between the local host and vm nodes",
+		Args:  cobra.ExactArgs(2),
+		Example: `sealvm cp ./sealos default-master-0:/usr/bin/sealos
+sealvm cp ./manifests master:/root/
+sealvm cp node:1:/var/log/kubelet.log ./
+sealvm cp node:/etc/kubernetes ./backup`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			i, err := process.NewInterfaceFromName(name)
+			if err != nil {
+				return err
+			}
+			return process.Copy(i.VMInfo(), args[0], args[1])
+		},
+	}
+	cpCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	return cpCmd
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/labring/sealvm/pkg/actions"
+	"github.com/spf13/cobra"
+)
+
+func newExecCmd() *cobra.Command {
+	var opts actions.ExecOptions
+	var execCmd = &cobra.Command{
+		Use:   "exec [--role r] [--hosts a,b] -- <command>",
+		Short: "Run a command on vm nodes without an action file",
+		Args:  cobra.MinimumNArgs(1),
+		Example: `sealvm exec -- uptime
+sealvm exec --role node -- systemctl is-active kubelet
+sealvm exec --hosts default-master-0,node:1 --parallel 1 -- df -h /
+sealvm exec -o json -- cat /etc/os-release`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return actions.Exec(name, strings.Join(args, " "), opts)
+		},
+	}
+	execCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	execCmd.Flags().StringSliceVar(&opts.Roles, "role", nil, "run the command on all the hosts of the roles")
+	execCmd.Flags().StringSliceVar(&opts.Hosts, "hosts", nil, "run the command on the hosts, a host is a host name, a role for its first host or role:index")
+	execCmd.Flags().IntVar(&opts.Parallel, "parallel", 0, "max number of hosts running the command at the same time, 0 is no limit")
+	execCmd.Flags().StringVarP(&opts.Output, "output", "o", actions.OutputText, "output format, text or json")
+	return execCmd
+}
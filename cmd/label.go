@@ -0,0 +1,41 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/labring/sealvm/pkg/process"
+	"github.com/spf13/cobra"
+)
+
+func newLabelCmd() *cobra.Command {
+	var labelCmd = &cobra.Command{
+		Use:   "label <host> k=v [k-]...",
+		Short: "Update the labels of a vm node",
+		Args:  cobra.MinimumNArgs(2),
+		Example: `sealvm label default-node-0 etcd=true gpu-sim=true
+sealvm label default-node-0 gpu-sim-`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			i, err := process.NewInterfaceFromName(name)
+			if err != nil {
+				return err
+			}
+			return i.Label(args[0], args[1:])
+		},
+	}
+	labelCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	return labelCmd
+}
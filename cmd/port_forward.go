@@ -0,0 +1,79 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/labring/sealvm/pkg/process"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"github.com/spf13/cobra"
+)
+
+func newPortForwardCmd() *cobra.Command {
+	var locals, remotes []string
+	var spec bool
+	var portForwardCmd = &cobra.Command{
+		Use:   "port-forward <host> [<local>:<remote>...]",
+		Short: "Forward local ports to a vm node or ports of a vm node to the local host until interrupted",
+		Long: `Forward local ports to a vm node or ports of a vm node to the local host until interrupted.
+
+The forwards saved in the cluster spec by 'sealvm run --forward' are not established by run or start,
+'sealvm port-forward --spec' is the only way to restore them, the forwards of the arguments are added to them.`,
+		Example: `sealvm port-forward master 6443:6443
+sealvm port-forward node:1 0.0.0.0:30080:127.0.0.1:30080
+sealvm port-forward default-node-0 -R 8080:3000
+sealvm port-forward --spec
+sealvm port-forward master --spec 8080:80`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !spec && len(args) == 0 {
+				return errors.New("host is required")
+			}
+			if !spec && len(args) == 1 && len(locals) == 0 && len(remotes) == 0 {
+				return errors.New("at least one forward is required")
+			}
+			i, err := process.NewInterfaceFromName(name)
+			if err != nil {
+				return err
+			}
+			vm := i.VMInfo()
+			var forwards []v1.PortForward
+			if spec {
+				forwards = append(forwards, vm.Spec.Forwards...)
+			}
+			if len(args) > 0 {
+				fs, err := process.ParsePortForwards(args[0], append(args[1:], locals...), remotes)
+				if err != nil {
+					return err
+				}
+				forwards = append(forwards, fs...)
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return process.PortForward(ctx, vm, forwards)
+		},
+	}
+	portForwardCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	portForwardCmd.Flags().StringSliceVarP(&locals, "local", "L", []string{}, "forward the local address to the address of the host, like ssh -L, eg: 6443:6443")
+	portForwardCmd.Flags().StringSliceVarP(&remotes, "remote", "R", []string{}, "forward the address of the host to the local address, like ssh -R, eg: 8080:3000")
+	portForwardCmd.Flags().BoolVar(&spec, "spec", false, "establish the forwards saved in the cluster spec, it is the only way to restore them after run or start")
+	return portForwardCmd
+}
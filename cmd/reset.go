@@ -18,6 +18,7 @@ package cmd
 import (
 	"errors"
 	"github.com/labring/sealvm/pkg/apply"
+	"github.com/labring/sealvm/pkg/process"
 	"github.com/labring/sealvm/pkg/utils/confirm"
 	v1 "github.com/labring/sealvm/types/api/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,7 +37,10 @@ func newResetCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return applier.Apply()
+			if err = applier.Apply(); err != nil {
+				return err
+			}
+			return process.UninstallSSHConfig(vm.Name)
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if yes, err := confirm.Confirm("Are you sure to reset the vms?", "you have canceled to delete these vms !"); err != nil {
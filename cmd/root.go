@@ -26,6 +26,7 @@ import (
 	"runtime"
 
 	"github.com/labring/sealvm/pkg/configs"
+	"github.com/labring/sealvm/pkg/ssh"
 	"github.com/labring/sealvm/pkg/utils/file"
 	"github.com/labring/sealvm/pkg/utils/logger"
 	"github.com/spf13/cobra"
@@ -36,6 +37,7 @@ var (
 	debug          bool
 	name           string
 	clusterRootDir string
+	insecure       bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -61,6 +63,7 @@ func init() {
 
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug logger")
 	rootCmd.PersistentFlags().StringVar(&clusterRootDir, "cluster-root", path.Join(file.GetHomeDir(), ".sealvm"), "cluster root directory")
+	rootCmd.PersistentFlags().BoolVar(&insecure, "insecure", false, "skip the verification of the ssh host keys of the vms")
 
 	groups := templates.CommandGroups{
 		{
@@ -69,14 +72,22 @@ func init() {
 				//newApplyCmd(),
 				newRunCmd(),
 				newResetCmd(),
+				newStartCmd(),
 				newInspectCmd(),
 				newListCmd(),
+				newLabelCmd(),
 			},
 		},
 		{
 			Message: "Remote Operation Commands:",
 			Commands: []*cobra.Command{
 				newActionCmd(),
+				newShellCmd(),
+				newExecCmd(),
+				newCpCmd(),
+				newPortForwardCmd(),
+				newSSHConfigCmd(),
+				newSyncCmd(),
 			},
 		},
 		{
@@ -98,6 +109,7 @@ func onBootOnDie() {
 		logger.Fatal("only support darwin and windows")
 	}
 	configs.DefaultClusterRootfsDir = clusterRootDir
+	ssh.SkipHostKeyVerify = insecure
 	var rootDirs = []string{
 		path.Join(clusterRootDir, "logs"),
 		path.Join(clusterRootDir, "data"),
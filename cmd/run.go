@@ -36,6 +36,9 @@ func newRunCmd() *cobra.Command {
 	vm := v1.VirtualMachine{}
 	val := template.NewValues()
 	var nodes string
+	var labels []string
+	var hooks v1.Hooks
+	var forwards, reverseForwards []string
 	//var defaultMount = fmt.Sprintf("%s:%s", path.Join(os.Getenv("GOPATH"), "src"), "/root/go/src")
 	var defaultImage string
 	var runCmd = &cobra.Command{
@@ -85,6 +88,10 @@ func newRunCmd() *cobra.Command {
 			if err != nil {
 				return errors.WithMessage(err, "parse nodes error")
 			}
+			labelMap, err := apply.ParseLabels(labels)
+			if err != nil {
+				return errors.WithMessage(err, "parse labels error")
+			}
 
 			for n, node := range nodeMap {
 				vm.Spec.Hosts = append(vm.Spec.Hosts, v1.Host{
@@ -95,9 +102,16 @@ func newRunCmd() *cobra.Command {
 						v1.DISKKey: defaultDiskGb,
 						v1.MEMKey:  defaultMemoryGb,
 					},
-					Image: defaultImage,
+					Image:  defaultImage,
+					Labels: labelMap[n],
 				})
 			}
+			if vm.Spec.Hooks, err = apply.ParseHooks(hooks); err != nil {
+				return errors.WithMessage(err, "parse hooks error")
+			}
+			if vm.Spec.Forwards, err = apply.ParseForwards(forwards, reverseForwards); err != nil {
+				return errors.WithMessage(err, "parse forwards error")
+			}
 			vm.Spec.SSH.PublicFile = val.Get("PublicKey")
 			vm.Spec.SSH.PkFile = val.Get("PrivateKey")
 			data, err := yaml.Marshal(&vm)
@@ -120,8 +134,21 @@ func newRunCmd() *cobra.Command {
 		},
 	}
 	runCmd.Flags().StringVar(&vm.Spec.SSH.PkPasswd, "pk-passwd", "", "passphrase for decrypting a PEM encoded private key")
+	runCmd.Flags().StringVar(&vm.Spec.SSH.CertFile, "ssh-cert", "", "OpenSSH certificate of the private key, default is <private key>-cert.pub if it exists")
+	runCmd.Flags().StringSliceVar(&vm.Spec.SSH.AuthMethods, "ssh-auth-methods", []string{}, "ssh auth methods tried in order, publickey, agent and password, default is all of them in this order. The keys of publickey and agent are tried together at the position of the first of them")
+	runCmd.Flags().BoolVar(&vm.Spec.SSH.ForwardAgent, "forward-agent", false, "forward the ssh agent of SSH_AUTH_SOCK to the vms")
+	runCmd.Flags().StringVar(&vm.Spec.SSH.User, "ssh-user", "", "user to login the vms, default is root")
+	runCmd.Flags().IntVar(&vm.Spec.SSH.Port, "ssh-port", 0, "ssh port of the vms, default is 22")
+	runCmd.Flags().StringVarP(&vm.Spec.SSH.ProxyJump, "proxy-jump", "J", "", "jump hosts to reach the vms in order, [user@]host[:port] separated by commas like ssh -J")
 	runCmd.Flags().StringVar(&vm.Name, "name", "default", "name of cluster to applied init action")
 	runCmd.Flags().StringVarP(&nodes, "nodes", "n", "", "number of nodes, eg: node:1,node2:2")
+	runCmd.Flags().StringSliceVarP(&labels, "labels", "l", []string{}, "labels of the role, eg: master@etcd=true")
+	runCmd.Flags().StringSliceVar(&hooks.PostCreate, "post-create", []string{}, "action files run on all hosts after the vms are created")
+	runCmd.Flags().StringSliceVar(&hooks.PostScaleUp, "post-scale-up", []string{}, "action files run only on the new hosts after scaling up")
+	runCmd.Flags().StringSliceVar(&hooks.PreDelete, "pre-delete", []string{}, "action files run on the hosts before they are deleted")
+	runCmd.Flags().StringSliceVar(&hooks.PostStart, "post-start", []string{}, "action files run on the stopped hosts after they are started")
+	runCmd.Flags().StringSliceVar(&forwards, "forward", []string{}, "local forwards saved in the spec and established by 'sealvm port-forward --spec', eg: master@6443:6443")
+	runCmd.Flags().StringSliceVar(&reverseForwards, "reverse-forward", []string{}, "reverse forwards saved in the spec and established by 'sealvm port-forward --spec', eg: master@8080:3000")
 	return runCmd
 }
 
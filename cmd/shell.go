@@ -0,0 +1,66 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/process"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/spf13/cobra"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func newShellCmd() *cobra.Command {
+	var shellCmd = &cobra.Command{
+		Use:     "shell <host|role[:index]> [-- cmd...]",
+		Aliases: []string{"ssh"},
+		Short:   "Open an interactive shell on a vm node, or run a command in it",
+		Args:    cobra.MinimumNArgs(1),
+		Example: `sealvm shell default-node-0
+sealvm shell master
+sealvm shell node:1 -- systemctl status kubelet`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			i, err := process.NewInterfaceFromName(name)
+			if err != nil {
+				return err
+			}
+			vm := i.VMInfo()
+			host, err := process.ResolveHost(vm, args[0])
+			if err != nil {
+				return err
+			}
+			client, addr, err := process.NewHostSSHClient(vm, host)
+			if err != nil {
+				return err
+			}
+			logger.Debug("open shell on %s by %s", host.ID, addr)
+			err = client.Shell(addr, strings.Join(args[1:], " "))
+			_ = client.Close()
+			var exitErr *gossh.ExitError
+			if errors.As(err, &exitErr) {
+				// the exit status of the remote shell is the exit status of sealvm, like ssh
+				os.Exit(exitErr.ExitStatus())
+			}
+			return err
+		},
+	}
+	shellCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	return shellCmd
+}
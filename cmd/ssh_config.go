@@ -0,0 +1,67 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/labring/sealvm/pkg/process"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/spf13/cobra"
+)
+
+func newSSHConfigCmd() *cobra.Command {
+	var install, uninstall, etcHosts bool
+	var sshConfigCmd = &cobra.Command{
+		Use:   "ssh-config",
+		Short: "Print the OpenSSH config of the vm nodes, or install it to connect the nodes by name",
+		Args:  cobra.NoArgs,
+		Example: `sealvm ssh-config --name default >> ~/.ssh/config
+sealvm ssh-config --install
+sealvm ssh-config --etc-hosts | sudo tee -a /etc/hosts`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if uninstall {
+				return process.UninstallSSHConfig(name)
+			}
+			i, err := process.NewInterfaceFromName(name)
+			if err != nil {
+				return err
+			}
+			vm := i.VMInfo()
+			if etcHosts {
+				fmt.Print(process.EtcHosts(vm))
+				return nil
+			}
+			if !install {
+				fmt.Print(process.SSHConfig(vm))
+				return nil
+			}
+			file, err := process.InstallSSHConfig(vm)
+			if err != nil {
+				return err
+			}
+			logger.Info("ssh config of cluster %s is installed to %s, the nodes can be connected by 'ssh <node name>'", name, file)
+			return nil
+		},
+	}
+	sshConfigCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	sshConfigCmd.Flags().BoolVar(&install, "install", false, "write the ssh config to ~/.ssh/sealvm/<name>.conf and include it in ~/.ssh/config")
+	sshConfigCmd.Flags().BoolVar(&uninstall, "uninstall", false, "remove the ssh config installed by --install")
+	sshConfigCmd.Flags().BoolVar(&etcHosts, "etc-hosts", false, "print the /etc/hosts entries of the vm nodes instead")
+	sshConfigCmd.MarkFlagsMutuallyExclusive("install", "uninstall", "etc-hosts")
+	return sshConfigCmd
+}
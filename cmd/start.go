@@ -0,0 +1,49 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/labring/sealvm/pkg/apply"
+	"github.com/labring/sealvm/pkg/configs"
+	"github.com/spf13/cobra"
+)
+
+func newStartCmd() *cobra.Command {
+	var startCmd = &cobra.Command{
+		Use:     "start",
+		Short:   "Start the stopped vm nodes and run the postStart hooks on them",
+		Example: `sealvm start -n default`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf := configs.NewVirtualMachineFile(name)
+			if err := cf.Process(); err != nil {
+				return err
+			}
+			// the saved spec is applied again, the stopped hosts are started by the reconcile
+			applier, err := apply.NewApplierFromArgs(cf.GetVirtualMachine().DeepCopy())
+			if err != nil {
+				return err
+			}
+			return applier.Apply()
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return checkProvider()
+		},
+	}
+	startCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	return startCmd
+}
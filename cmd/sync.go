@@ -0,0 +1,56 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labring/sealvm/pkg/process"
+	"github.com/spf13/cobra"
+)
+
+func newSyncCmd() *cobra.Command {
+	opts := process.SyncOptions{}
+	var syncCmd = &cobra.Command{
+		Use:   "sync <localdir> <host>:<remotedir>",
+		Short: "Sync a local directory to vm nodes, and keep pushing its changes with --watch",
+		Args:  cobra.ExactArgs(2),
+		Example: `sealvm sync ./sealos node:/root/sealos
+sealvm sync ./sealos master:/root/sealos --watch
+sealvm sync ./sealos default-node-0:/root/sealos --watch --delete --exclude '*.tar'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			i, err := process.NewInterfaceFromName(name)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return process.Sync(ctx, i.VMInfo(), args[0], args[1], opts)
+		},
+	}
+	syncCmd.Flags().StringVarP(&name, "name", "n", "default", "name of cluster to applied init action")
+	syncCmd.Flags().BoolVarP(&opts.Watch, "watch", "w", false, "keep pushing the changes of the local directory until interrupted")
+	syncCmd.Flags().BoolVar(&opts.Delete, "delete", false, "delete the remote files which do not exist in the local directory at the first sync")
+	syncCmd.Flags().StringSliceVar(&opts.Excludes, "exclude", []string{}, "extra patterns ignored like the lines of .gitignore, .git is always ignored")
+	syncCmd.Flags().DurationVar(&opts.Interval, "interval", 500*time.Millisecond, "interval of checking the local directory for changes when it can not be watched, and of retrying a failed push")
+	syncCmd.Flags().DurationVar(&opts.Debounce, "debounce", 300*time.Millisecond, "how long the local directory must be unchanged before the changes are pushed")
+	return syncCmd
+}
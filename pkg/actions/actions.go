@@ -18,69 +18,195 @@ package actions
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/labring/sealvm/pkg/actions/library"
 	"github.com/labring/sealvm/pkg/actions/runtime"
 	"github.com/labring/sealvm/pkg/utils/confirm"
 	"github.com/labring/sealvm/pkg/utils/file"
 	"github.com/labring/sealvm/pkg/utils/logger"
 	yutil "github.com/labring/sealvm/pkg/utils/yaml"
 	v1 "github.com/labring/sealvm/types/api/v1"
+	"github.com/modood/table"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/errors"
-	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
-func Do(name, p string) error {
+// Options are the options of running the actions of a file.
+type Options struct {
+	// Only runs only the action of the name
+	Only string
+	// From runs the action of the name and all actions depending on it
+	From string
+	// Yes skips the confirmation
+	Yes bool
+	// ReportFormat writes a junit or json report of the results when it is set
+	ReportFormat string
+	// ReportFile path of the report
+	ReportFile string
+	// Checksum reports every file of the copy steps
+	Checksum bool
+}
+
+// Do applies the actions of the file as a workflow, it returns an error when any action does not complete.
+func Do(name, p string, opts Options) error {
 	if !file.IsExist(p) {
 		return fmt.Errorf("file %s not exist", p)
 	}
+	if opts.ReportFormat != "" && opts.ReportFormat != ReportJUnit && opts.ReportFormat != ReportJSON {
+		return fmt.Errorf("report format %s is not supported, only %s and %s", opts.ReportFormat, ReportJUnit, ReportJSON)
+	}
 	data, err := file.ReadAll(p)
 	if err != nil {
 		return err
 	}
-	logger.Info("action yamls: %s", string(data))
-	if yes, err := confirm.Confirm("Are you sure to run this command?", "you have canceled to exec action !"); err != nil {
+	actions, err := loadActions(data)
+	if err != nil {
 		return err
-	} else {
-		if !yes {
-			return fmt.Errorf("you have canceled to exec action ")
-		}
 	}
-	yamls := yutil.ToYalms(string(data))
-	actions := make([]v1.Action, 0)
-	for _, y := range yamls {
-		action := v1.Action{}
-		err = yaml.Unmarshal([]byte(y), &action)
-		if err != nil {
-			logger.Warn("unmarshal action error: %v", err)
-			continue
-		}
-		actions = append(actions, action)
+	wf, err := newWorkflow(actions)
+	if err != nil {
+		return err
+	}
+	if err = wf.Select(opts.Only, opts.From); err != nil {
+		return err
 	}
 	r, err := runtime.NewAction(name)
 	if err != nil {
 		return err
 	}
-	errArr := make([]error, 0)
-	newActions := make([]any, 0)
-	for index, action := range actions {
-		if err = r.Apply(&action); err != nil {
-			logger.Error("apply action %d error: %v", index, err)
-			errArr = append(errArr, err)
+	defer r.Close()
+	if err = validateOns(r, actions); err != nil {
+		return err
+	}
+	logger.Info("action yamls: %s", string(data))
+	if !opts.Yes {
+		if yes, err := confirm.Confirm("Are you sure to run this command?", "you have canceled to exec action !"); err != nil {
+			return err
+		} else {
+			if !yes {
+				return fmt.Errorf("you have canceled to exec action ")
+			}
 		}
-		newActions = append(newActions, action)
+	}
+	r.WithChecksum(opts.Checksum)
+	var (
+		lock  sync.Mutex
+		steps []runtime.StepResult
+	)
+	results := wf.Run(func(action *v1.Action) error {
+		rt := r.Fork()
+		err := rt.Apply(action)
+		lock.Lock()
+		steps = append(steps, rt.Results()...)
+		lock.Unlock()
+		return err
+	})
+	newActions := make([]any, 0)
+	for _, n := range wf.Nodes() {
+		newActions = append(newActions, *n.action)
 	}
 
 	outActionfile, _ := yutil.MarshalYamlConfigs(newActions...)
 
 	logger.Info("outActionfile: %s", string(outActionfile))
 
-	if len(errArr) > 0 {
-		logger.Error("apply actions error: %v", errors.NewAggregate(errArr).Error())
-		return nil
+	table.OutputA(results)
+
+	errArr := make([]error, 0)
+	if opts.ReportFormat != "" {
+		reportFile := opts.ReportFile
+		if reportFile == "" {
+			reportFile = DefaultReportFile(opts.ReportFormat)
+		}
+		// a missing report fails the run, the ci must not pass without it
+		if err = WriteReport(opts.ReportFormat, reportFile, results, steps); err != nil {
+			errArr = append(errArr, fmt.Errorf("failed to write %s report %s: %v", opts.ReportFormat, reportFile, err))
+		} else {
+			logger.Info("%s report is written to %s", opts.ReportFormat, reportFile)
+		}
+	}
+	for _, result := range results {
+		if result.Phase != v1.ActionPhaseComplete {
+			errArr = append(errArr, fmt.Errorf("action %s is %s: %s", result.Name, result.Phase, result.Message))
+		}
+	}
+	return errors.NewAggregate(errArr)
+}
+
+// Plan prints what every action in the file would do on each host without touching any vm.
+func Plan(name, p string, opts Options) error {
+	if !file.IsExist(p) {
+		return fmt.Errorf("file %s not exist", p)
+	}
+	data, err := file.ReadAll(p)
+	if err != nil {
+		return err
+	}
+	r, err := runtime.NewAction(name)
+	if err != nil {
+		return err
+	}
+	actions, err := loadActions(data)
+	if err != nil {
+		return err
+	}
+	wf, err := newWorkflow(actions)
+	if err != nil {
+		return err
+	}
+	if err = wf.Select(opts.Only, opts.From); err != nil {
+		return err
+	}
+	if err = validateOns(r, actions); err != nil {
+		return err
+	}
+	for _, n := range wf.Nodes() {
+		if len(n.deps) > 0 {
+			fmt.Printf("action %s (depends on %s):\n", n.name, strings.Join(n.deps, ","))
+		} else {
+			fmt.Printf("action %s:\n", n.name)
+		}
+		r.Plan(n.action).Print(os.Stdout)
 	}
 	return nil
 }
 
+// loadActions decodes the actions of data strictly, every document must be a valid action.
+// The errors contain the document and the line and column of the yaml.
+func loadActions(data []byte) ([]v1.Action, error) {
+	docs, err := yutil.Documents(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid yaml: %v", err)
+	}
+	actions := make([]v1.Action, 0)
+	for i, doc := range docs {
+		action := v1.Action{}
+		if err = yutil.UnmarshalNodeStrict(doc, &action); err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		if action.Kind != "Action" || action.APIVersion != v1.GroupVersion.String() {
+			return nil, fmt.Errorf("document %d: want kind Action of %s, got kind %q of %q", i+1, v1.GroupVersion.String(), action.Kind, action.APIVersion)
+		}
+		if err = validateAction(&action); err != nil {
+			return nil, fmt.Errorf("action %s: %v", action.Name, err)
+		}
+		expanded, err := library.Expand(action.Spec.Data)
+		if err != nil {
+			return nil, err
+		}
+		action.Spec.Data = expanded
+		// the steps of the library actions are checked after they are rendered
+		if err = validateAction(&action); err != nil {
+			return nil, fmt.Errorf("action %s: %v", action.Name, err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
 func PrintDefault() error {
 	actions := make([]any, 0)
 	action := v1.Action{
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/actions/runtime"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/modood/table"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// ExecOptions are the options of running a command on the hosts without an action file.
+type ExecOptions struct {
+	// Roles runs the command on all the hosts of the roles
+	Roles []string
+	// Hosts runs the command on the hosts, a host is a host name, a role for its first host or role:index
+	Hosts []string
+	// Parallel max number of hosts running the command at the same time, 0 is no limit
+	Parallel int
+	// Output text prints the output lines of the hosts and a summary, json prints the results only
+	Output string
+}
+
+// execSummary is a row of the summary of Exec.
+type execSummary struct {
+	Host     string
+	ExitCode int
+	Duration string
+	Error    string
+}
+
+// Exec runs the command on the hosts of the cluster, all the hosts when no role and host is set.
+// It returns an error when the command fails on any host.
+func Exec(name, cmd string, opts ExecOptions) error {
+	if opts.Output != OutputText && opts.Output != OutputJSON {
+		return fmt.Errorf("output %s is not supported, only %s and %s", opts.Output, OutputText, OutputJSON)
+	}
+	if strings.TrimSpace(cmd) == "" {
+		return fmt.Errorf("command is empty")
+	}
+	if opts.Parallel < 0 {
+		return fmt.Errorf("parallel must not be negative")
+	}
+	r, err := runtime.NewRunAction(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	names, err := r.Targets(opts.Roles, opts.Hosts)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no host is selected in cluster %s", name)
+	}
+	logger.Debug("exec %s on %v", cmd, names)
+	results := r.Run(names, cmd, runtime.RunOptions{Parallel: opts.Parallel, Quiet: opts.Output == OutputJSON})
+
+	failed := make([]string, 0)
+	summary := make([]execSummary, 0, len(results))
+	for _, result := range results {
+		if result.ExitCode != 0 {
+			failed = append(failed, result.Host)
+		}
+		summary = append(summary, execSummary{Host: result.Host, ExitCode: result.ExitCode, Duration: result.Duration, Error: result.Error})
+	}
+	if opts.Output == OutputJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		table.OutputA(summary)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("command failed on %d of %d hosts: %s", len(failed), len(results), strings.Join(failed, ","))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+
+	"github.com/labring/sealvm/pkg/actions/runtime"
+	"github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"github.com/modood/table"
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// RunHook applies the actions of the hook file on the vm without confirmation.
+// When hosts is not nil the actions only run on these hosts, it returns an error if any action does not complete.
+func RunHook(vm *v1.VirtualMachine, p string, hosts []string) error {
+	if !file.IsExist(p) {
+		return fmt.Errorf("hook file %s not exist", p)
+	}
+	data, err := file.ReadAll(p)
+	if err != nil {
+		return err
+	}
+	actions, err := loadActions(data)
+	if err != nil {
+		return err
+	}
+	wf, err := newWorkflow(actions)
+	if err != nil {
+		return err
+	}
+	r := runtime.NewActionFromVM(vm).WithHosts(hosts)
+	defer r.Close()
+	if err = validateOns(r, actions); err != nil {
+		return err
+	}
+	logger.Info("run hook %s on hosts %v", p, hosts)
+	results := wf.Run(func(action *v1.Action) error {
+		return r.Fork().Apply(action)
+	})
+	table.OutputA(results)
+	errArr := make([]error, 0)
+	for _, result := range results {
+		if result.Phase != v1.ActionPhaseComplete {
+			errArr = append(errArr, fmt.Errorf("action %s is %s: %s", result.Name, result.Phase, result.Message))
+		}
+	}
+	return errors.NewAggregate(errArr)
+}
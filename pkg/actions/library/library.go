@@ -0,0 +1,248 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/configs"
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/labring/sealvm/pkg/utils/template"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+const definitionSuffix = ".yaml"
+
+// maxDepth limits how deep library actions may use other library actions.
+const maxDepth = 10
+
+// varsRegex matches the ${{ }} variables rendered by the runtime when the step runs.
+var varsRegex = regexp.MustCompile(`(?s)\$\{\{.*?\}\}`)
+
+// Param is a parameter of a library action, it is referenced in the data as {{ .name }}.
+// The ${{ }} variables of the steps are not params, they are kept for the runtime.
+type Param struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Definition is a named, parameterized list of action steps stored in the library.
+type Definition struct {
+	Description string          `json:"description,omitempty"`
+	Params      []Param         `json:"params,omitempty"`
+	Data        []v1.ActionData `json:"data,omitempty"`
+}
+
+// Dir is the directory of the action library.
+func Dir() string {
+	return path.Join(configs.DefaultRootfsDir(), "actions")
+}
+
+func definitionPath(name string) string {
+	return path.Join(Dir(), fmt.Sprintf("%s%s", name, definitionSuffix))
+}
+
+// List returns the names of all library actions.
+func List() ([]string, error) {
+	if !fileutil.IsExist(Dir()) {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(Dir())
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), definitionSuffix) {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(e.Name(), definitionSuffix))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get loads the library action of the given name.
+func Get(name string) (*Definition, error) {
+	p := definitionPath(name)
+	if !fileutil.IsExist(p) {
+		return nil, fmt.Errorf("library action %s is not exist", name)
+	}
+	data, err := fileutil.ReadAll(p)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes a library action definition.
+func Parse(data []byte) (*Definition, error) {
+	d := &Definition{}
+	if err := yaml.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("failed to decode library action: %v", err)
+	}
+	if len(d.Data) == 0 {
+		return nil, fmt.Errorf("library action has no data")
+	}
+	names := sets.NewString()
+	for _, p := range d.Params {
+		if p.Name == "" {
+			return nil, fmt.Errorf("library action param name is empty")
+		}
+		if names.Has(p.Name) {
+			return nil, fmt.Errorf("library action param %s is duplicated", p.Name)
+		}
+		names.Insert(p.Name)
+	}
+	return d, nil
+}
+
+// Add validates the definition file and stores it into the library as name.
+func Add(name, file string, force bool) error {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid library action name %q", name)
+	}
+	data, err := fileutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	if _, err = Parse(data); err != nil {
+		return err
+	}
+	p := definitionPath(name)
+	if fileutil.IsExist(p) && !force {
+		return fmt.Errorf("library action %s already exists, use --force to overwrite it", name)
+	}
+	if err = fileutil.WriteFile(p, data); err != nil {
+		return err
+	}
+	logger.Info("Sync library action %s success.", name)
+	return nil
+}
+
+// Render returns the steps of the definition with the params replaced by values.
+func (d *Definition) Render(name string, with map[string]string) ([]v1.ActionData, error) {
+	values := make(map[string]string)
+	known := sets.NewString()
+	for _, p := range d.Params {
+		known.Insert(p.Name)
+		if v, ok := with[p.Name]; ok {
+			values[p.Name] = v
+			continue
+		}
+		if p.Required {
+			return nil, fmt.Errorf("library action %s requires param %s", name, p.Name)
+		}
+		values[p.Name] = p.Default
+	}
+	for k := range with {
+		if !known.Has(k) {
+			return nil, fmt.Errorf("library action %s has no param %s", name, k)
+		}
+	}
+	data := make([]v1.ActionData, 0, len(d.Data))
+	for i, step := range d.Data {
+		rendered, err := renderStep(name, step, values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render step %d of library action %s: %v", i, name, err)
+		}
+		data = append(data, rendered)
+	}
+	return data, nil
+}
+
+// renderStep renders the params in every string of the step on its own, so the values are never parsed as yaml.
+func renderStep(name string, step v1.ActionData, values map[string]string) (v1.ActionData, error) {
+	out := v1.ActionData{}
+	if err := template.RenderStrings(step, &out, func(s string) (string, error) {
+		return renderParams(name, s, values)
+	}); err != nil {
+		return step, err
+	}
+	return out, nil
+}
+
+// renderParams renders the params in s, the ${{ }} variables are written as they are for the runtime.
+func renderParams(name, s string, values map[string]string) (string, error) {
+	if !strings.Contains(varsRegex.ReplaceAllString(s, ""), "{{") {
+		return s, nil
+	}
+	text := varsRegex.ReplaceAllStringFunc(s, func(v string) string {
+		return "{{" + strconv.Quote(v) + "}}"
+	})
+	tpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	out := bytes.NewBuffer(nil)
+	if err = tpl.Execute(out, values); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Expand replaces every `use` step with the rendered steps of the library action.
+func Expand(data []v1.ActionData) ([]v1.ActionData, error) {
+	return expand(data, nil, Get)
+}
+
+func expand(data []v1.ActionData, stack []string, get func(name string) (*Definition, error)) ([]v1.ActionData, error) {
+	if len(stack) > maxDepth {
+		return nil, fmt.Errorf("library actions are nested too deep: %s", strings.Join(stack, " -> "))
+	}
+	expanded := make([]v1.ActionData, 0, len(data))
+	for _, step := range data {
+		if step.ActionUse == "" {
+			if len(step.ActionWith) != 0 {
+				return nil, fmt.Errorf("with is only supported by use steps")
+			}
+			expanded = append(expanded, step)
+			continue
+		}
+		for _, s := range stack {
+			if s == step.ActionUse {
+				return nil, fmt.Errorf("library action cycle detected: %s -> %s", strings.Join(stack, " -> "), step.ActionUse)
+			}
+		}
+		d, err := get(step.ActionUse)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := d.Render(step.ActionUse, step.ActionWith)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err = expand(rendered, append(stack, step.ActionUse), get)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, rendered...)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package library
+
+import (
+	"fmt"
+
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/modood/table"
+	"github.com/spf13/cobra"
+)
+
+func NewLibCmd() *cobra.Command {
+	var libCmd = &cobra.Command{
+		Use:   "lib",
+		Short: "Manage the library of reusable actions",
+		Long: `Library actions are stored in ~/.sealvm/actions/<name>.yaml and are used in an action by a step:
+
+  - use: golang-install
+    with:
+      version: 1.20.1`,
+	}
+	libCmd.AddCommand(newLibListCmd())
+	libCmd.AddCommand(newLibShowCmd())
+	libCmd.AddCommand(newLibAddCmd())
+	return libCmd
+}
+
+func newLibListCmd() *cobra.Command {
+	var listCmd = &cobra.Command{
+		Use:     "list",
+		Short:   "Print a list of library actions",
+		Args:    cobra.NoArgs,
+		Example: `sealvm action lib list`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := List()
+			if err != nil {
+				return err
+			}
+			type libPrint struct {
+				Name        string
+				Description string
+				AbsPath     string
+			}
+			prints := make([]libPrint, 0)
+			for _, name := range names {
+				d, err := Get(name)
+				if err != nil {
+					logger.Warn("load library action %s error: %v", name, err)
+					continue
+				}
+				prints = append(prints, libPrint{
+					Name:        name,
+					Description: d.Description,
+					AbsPath:     definitionPath(name),
+				})
+			}
+			if len(prints) == 0 {
+				logger.Info("Print library action list lens is 0.")
+				return nil
+			}
+			table.OutputA(prints)
+			return nil
+		},
+	}
+	return listCmd
+}
+
+func newLibShowCmd() *cobra.Command {
+	var showCmd = &cobra.Command{
+		Use:     "show <name>",
+		Short:   "Print the params and steps of a library action",
+		Args:    cobra.ExactArgs(1),
+		Example: `sealvm action lib show golang-install`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := Get(args[0])
+			if err != nil {
+				return err
+			}
+			if d.Description != "" {
+				fmt.Println(d.Description)
+			}
+			if len(d.Params) > 0 {
+				table.OutputA(d.Params)
+			}
+			data, _ := fileutil.ReadAll(definitionPath(args[0]))
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+	return showCmd
+}
+
+func newLibAddCmd() *cobra.Command {
+	var file string
+	var force bool
+	var addCmd = &cobra.Command{
+		Use:     "add <name>",
+		Short:   "Add a library action from a definition file",
+		Args:    cobra.ExactArgs(1),
+		Example: `sealvm action lib add golang-install -f golang-install.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Add(args[0], file, force)
+		},
+	}
+	addCmd.Flags().StringVarP(&file, "file", "f", "", "definition file of the library action")
+	addCmd.Flags().BoolVar(&force, "force", false, "overwrite the library action if it already exists")
+	_ = addCmd.MarkFlagRequired("file")
+	return addCmd
+}
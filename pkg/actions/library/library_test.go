@@ -0,0 +1,150 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package library
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+var testLibrary = map[string]string{
+	"golang-install": `
+description: install golang
+params:
+- name: version
+  default: 1.20.1
+- name: arch
+  required: true
+data:
+- exec: |
+    wget https://go.dev/dl/go{{ .version }}.linux-{{ .arch }}.tar.gz
+`,
+	"echo": `
+params:
+- name: msg
+data:
+- exec: echo {{ .msg }}
+- exec: echo '{{ .msg }}'
+`,
+	"login": `
+params:
+- name: user
+data:
+- exec: echo ${{ .vars.TOKEN }} | docker login -u {{ .user }} --password-stdin ${{ index .hostVars "master" "REGISTRY" }}
+`,
+	"loop-a": `
+data:
+- use: loop-b
+`,
+	"loop-b": `
+data:
+- use: loop-a
+`,
+}
+
+func testGet(name string) (*Definition, error) {
+	data, ok := testLibrary[name]
+	if !ok {
+		return nil, fmt.Errorf("library action %s is not exist", name)
+	}
+	return Parse([]byte(data))
+}
+
+func Test_expand(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []v1.ActionData
+		want    []v1.ActionData
+		wantErr bool
+	}{
+		{
+			name: "default params",
+			data: []v1.ActionData{
+				{ActionExec: "ls"},
+				{ActionUse: "golang-install", ActionWith: map[string]string{"arch": "arm64"}},
+			},
+			want: []v1.ActionData{
+				{ActionExec: "ls"},
+				{ActionExec: "wget https://go.dev/dl/go1.20.1.linux-arm64.tar.gz\n"},
+			},
+		},
+		{
+			name: "override params",
+			data: []v1.ActionData{
+				{ActionUse: "golang-install", ActionWith: map[string]string{"arch": "amd64", "version": "1.21.0"}},
+			},
+			want: []v1.ActionData{
+				{ActionExec: "wget https://go.dev/dl/go1.21.0.linux-amd64.tar.gz\n"},
+			},
+		},
+		{
+			name: "values are not yaml",
+			data: []v1.ActionData{
+				{ActionUse: "echo", ActionWith: map[string]string{"msg": "x # y"}},
+				{ActionUse: "echo", ActionWith: map[string]string{"msg": "a: b\nit's"}},
+			},
+			want: []v1.ActionData{
+				{ActionExec: "echo x # y"},
+				{ActionExec: "echo 'x # y'"},
+				{ActionExec: "echo a: b\nit's"},
+				{ActionExec: "echo 'a: b\nit's'"},
+			},
+		},
+		{
+			name: "params and variables",
+			data: []v1.ActionData{{ActionUse: "login", ActionWith: map[string]string{"user": "admin"}}},
+			want: []v1.ActionData{
+				{ActionExec: `echo ${{ .vars.TOKEN }} | docker login -u admin --password-stdin ${{ index .hostVars "master" "REGISTRY" }}`},
+			},
+		},
+		{
+			name:    "missing required param",
+			data:    []v1.ActionData{{ActionUse: "golang-install"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown param",
+			data:    []v1.ActionData{{ActionUse: "golang-install", ActionWith: map[string]string{"arch": "arm64", "os": "linux"}}},
+			wantErr: true,
+		},
+		{
+			name:    "not exist",
+			data:    []v1.ActionData{{ActionUse: "sealos-apt"}},
+			wantErr: true,
+		},
+		{
+			name:    "cycle",
+			data:    []v1.ActionData{{ActionUse: "loop-a"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expand(tt.data, nil, testGet)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expand() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
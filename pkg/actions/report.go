@@ -0,0 +1,212 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+
+	"github.com/labring/sealvm/pkg/actions/runtime"
+	"github.com/labring/sealvm/pkg/utils/file"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+const (
+	ReportJUnit = "junit"
+	ReportJSON  = "json"
+)
+
+// DefaultReportFile returns the default report file of the format.
+func DefaultReportFile(format string) string {
+	if format == ReportJSON {
+		return "sealvm-report.json"
+	}
+	return "sealvm-report.xml"
+}
+
+type jsonReport struct {
+	Actions []jsonAction `json:"actions"`
+}
+
+type jsonAction struct {
+	Name    string     `json:"name"`
+	Phase   string     `json:"phase"`
+	Message string     `json:"message,omitempty"`
+	Steps   []jsonStep `json:"steps"`
+}
+
+type jsonStep struct {
+	Step    int     `json:"step"`
+	Type    string  `json:"type"`
+	Host    string  `json:"host"`
+	Seconds float64 `json:"seconds"`
+	Stdout  string  `json:"stdout,omitempty"`
+	Stderr  string  `json:"stderr,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// stepsOf returns the step results of the action sorted by step and host.
+func stepsOf(name string, steps []runtime.StepResult) []runtime.StepResult {
+	out := make([]runtime.StepResult, 0)
+	for _, s := range steps {
+		if s.Action == name {
+			out = append(out, s)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Step != out[j].Step {
+			return out[i].Step < out[j].Step
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+func newJSONReport(results []WorkflowResult, steps []runtime.StepResult) jsonReport {
+	report := jsonReport{Actions: make([]jsonAction, 0, len(results))}
+	for _, r := range results {
+		a := jsonAction{
+			Name:    r.Name,
+			Phase:   string(r.Phase),
+			Message: r.Message,
+			Steps:   make([]jsonStep, 0),
+		}
+		for _, s := range stepsOf(r.Name, steps) {
+			a.Steps = append(a.Steps, jsonStep{
+				Step:    s.Step,
+				Type:    s.Type,
+				Host:    s.Host,
+				Seconds: s.Duration.Seconds(),
+				Stdout:  s.Stdout,
+				Stderr:  s.Stderr,
+				Error:   s.Error,
+			})
+		}
+		report.Actions = append(report.Actions, a)
+	}
+	return report
+}
+
+// newJUnitReport writes a test case per step per host, an action without steps gets a single test case
+// so skipped actions and actions failed before any step are still reported.
+func newJUnitReport(results []WorkflowResult, steps []runtime.StepResult) junitTestSuites {
+	report := junitTestSuites{Name: "sealvm"}
+	var total float64
+	for _, r := range results {
+		suite := junitTestSuite{Name: r.Name}
+		var suiteTime float64
+		actionSteps := stepsOf(r.Name, steps)
+		for _, s := range actionSteps {
+			c := junitTestCase{
+				Name:      fmt.Sprintf("step %d %s on %s", s.Step, s.Type, s.Host),
+				ClassName: r.Name,
+				Time:      fmt.Sprintf("%.3f", s.Duration.Seconds()),
+				SystemOut: s.Stdout,
+				SystemErr: s.Stderr,
+			}
+			if s.Error != "" {
+				c.Failure = &junitMessage{Message: s.Error, Body: s.Error}
+				suite.Failures++
+			}
+			suiteTime += s.Duration.Seconds()
+			suite.Cases = append(suite.Cases, c)
+		}
+		if len(actionSteps) == 0 {
+			c := junitTestCase{
+				Name:      "apply",
+				ClassName: r.Name,
+				Time:      "0.000",
+			}
+			switch r.Phase {
+			case v1.ActionPhaseSkipped:
+				c.Skipped = &junitMessage{Message: r.Message}
+				suite.Skipped++
+			case v1.ActionPhaseComplete:
+			default:
+				c.Failure = &junitMessage{Message: r.Message, Body: r.Message}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		suite.Tests = len(suite.Cases)
+		suite.Time = fmt.Sprintf("%.3f", suiteTime)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		total += suiteTime
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = fmt.Sprintf("%.3f", total)
+	return report
+}
+
+// WriteReport writes the results of the workflow to the file in the format of junit or json.
+func WriteReport(format, p string, results []WorkflowResult, steps []runtime.StepResult) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch format {
+	case ReportJSON:
+		data, err = json.MarshalIndent(newJSONReport(results, steps), "", "  ")
+	case ReportJUnit:
+		data, err = xml.MarshalIndent(newJUnitReport(results, steps), "", "  ")
+		data = append([]byte(xml.Header), data...)
+	default:
+		return fmt.Errorf("report format %s is not supported, only %s and %s", format, ReportJUnit, ReportJSON)
+	}
+	if err != nil {
+		return err
+	}
+	return file.WriteFile(p, data)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labring/sealvm/pkg/actions/runtime"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+var (
+	testResults = []WorkflowResult{
+		{Name: "install", Phase: v1.ActionPhaseFailed, Message: "exec failed"},
+		{Name: "verify", Phase: v1.ActionPhaseSkipped, Message: "dependencies install did not complete"},
+	}
+	testSteps = []runtime.StepResult{
+		{Action: "install", Step: 1, Type: "exec", Host: "default-node-0", Duration: time.Second, Stderr: "not found", Error: "exit status 127"},
+		{Action: "install", Step: 0, Type: "copy", Host: "default-node-0", Duration: 2 * time.Second},
+	}
+)
+
+func Test_newJUnitReport(t *testing.T) {
+	report := newJUnitReport(testResults, testSteps)
+	if report.Tests != 3 || report.Failures != 1 || report.Skipped != 1 {
+		t.Fatalf("newJUnitReport() tests=%d failures=%d skipped=%d, want 3 1 1", report.Tests, report.Failures, report.Skipped)
+	}
+	install := report.Suites[0]
+	if install.Cases[0].Name != "step 0 copy on default-node-0" || install.Cases[1].Failure == nil {
+		t.Errorf("newJUnitReport() cases = %+v", install.Cases)
+	}
+	if install.Time != "3.000" {
+		t.Errorf("newJUnitReport() time = %s, want 3.000", install.Time)
+	}
+	if report.Suites[1].Cases[0].Skipped == nil {
+		t.Errorf("newJUnitReport() skipped action is not reported as skipped")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "report.json")
+	if err := WriteReport(ReportJSON, p, testResults, testSteps); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := jsonReport{}
+	if err = json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Actions) != 2 || len(report.Actions[0].Steps) != 2 || report.Actions[0].Steps[1].Stderr != "not found" {
+		t.Errorf("WriteReport() json = %s", string(data))
+	}
+	p = filepath.Join(dir, "report.xml")
+	if err = WriteReport(ReportJUnit, p, testResults, testSteps); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(p)
+	if !strings.Contains(string(data), `<failure message="exit status 127">`) {
+		t.Errorf("WriteReport() junit = %s", string(data))
+	}
+	if err = WriteReport("html", p, testResults, testSteps); err == nil {
+		t.Errorf("WriteReport() want error of unsupported format")
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	osexec "os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"golang.org/x/crypto/ssh"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+func (m *action) Assert(names []string, data v1.ActionData) error {
+	a := data.ActionAssert
+	if a == nil {
+		return nil
+	}
+	if strings.TrimSpace(a.Command) == "" {
+		return fmt.Errorf("assert command is empty")
+	}
+	script, clean, err := newScript(data.ExecOptions, a.Command)
+	if err != nil {
+		return err
+	}
+	defer clean()
+	var (
+		lock     sync.Mutex
+		values   = make(map[string]string)
+		failures = make([]error, 0)
+	)
+	_ = m.runOnHosts(names, "assert", func(name string, stdout, stderr io.Writer) error {
+		outBuf, errBuf := &bytes.Buffer{}, &bytes.Buffer{}
+		runErr := m.ExecOnce(name, script, io.MultiWriter(outBuf, stdout), io.MultiWriter(errBuf, stderr))
+		code, ok := exitCode(runErr)
+		if !ok {
+			err := fmt.Errorf("assert on %s failed: %v", name, runErr)
+			lock.Lock()
+			failures = append(failures, err)
+			lock.Unlock()
+			return err
+		}
+		value, msgs := checkAssert(a, code, outBuf.String(), errBuf.String())
+		lock.Lock()
+		defer lock.Unlock()
+		values[name] = value
+		if len(msgs) > 0 {
+			err := fmt.Errorf("assert on %s failed: %s", name, strings.Join(msgs, "; "))
+			logger.Error(err)
+			failures = append(failures, err)
+			return err
+		}
+		logger.Info("%s: assert passed", name)
+		return nil
+	})
+	if a.SameAcrossHosts && len(failures) == 0 {
+		if err := sameAcrossHosts(values); err != nil {
+			logger.Error(err)
+			m.record(StepResult{Action: m.name, Step: m.step, Type: "assert", Host: strings.Join(names, ","), Error: err.Error()})
+			failures = append(failures, err)
+		}
+	}
+	return utilerrors.NewAggregate(failures)
+}
+
+// exitCode returns the exit code of the error of ExecOnce, ok is false if the command did not run to the end.
+func exitCode(err error) (int, bool) {
+	if err == nil {
+		return 0, true
+	}
+	var sshErr *ssh.ExitError
+	if errors.As(err, &sshErr) {
+		return sshErr.ExitStatus(), true
+	}
+	var execErr *osexec.ExitError
+	if errors.As(err, &execErr) {
+		return execErr.ExitCode(), true
+	}
+	return 0, false
+}
+
+// checkAssert checks the result of the command, it returns the checked stdout used to compare across hosts
+// and a message for every failed expectation.
+func checkAssert(a *v1.Assert, code int, stdout, stderr string) (string, []string) {
+	msgs := make([]string, 0)
+	want := 0
+	if a.ExitCode != nil {
+		want = *a.ExitCode
+	}
+	if code != want {
+		msgs = append(msgs, fmt.Sprintf("exit code is %d, want %d", code, want))
+	}
+	value := strings.TrimSpace(stdout)
+	if a.Stdout != nil {
+		v, err := checkOutput(a.Stdout, stdout)
+		if err != nil {
+			msgs = append(msgs, "stdout "+err.Error())
+		}
+		value = v
+	}
+	if a.Stderr != nil {
+		if _, err := checkOutput(a.Stderr, stderr); err != nil {
+			msgs = append(msgs, "stderr "+err.Error())
+		}
+	}
+	return value, msgs
+}
+
+// checkOutput checks the output against the expectations, it returns the checked value which is
+// the result of the jsonpath if it is set.
+func checkOutput(m *v1.OutputMatch, out string) (string, error) {
+	value := strings.TrimSpace(out)
+	if m.JSONPath != "" {
+		v, err := evalJSONPath(m.JSONPath, out)
+		if err != nil {
+			return value, err
+		}
+		value = v
+	}
+	if m.Equals != nil && value != strings.TrimSpace(*m.Equals) {
+		return value, fmt.Errorf("is %q, want %q", excerpt(value), *m.Equals)
+	}
+	if m.Contains != "" && !strings.Contains(value, m.Contains) {
+		return value, fmt.Errorf("%q does not contain %q", excerpt(value), m.Contains)
+	}
+	if m.Regex != "" {
+		re, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return value, fmt.Errorf("regex %s is invalid: %v", m.Regex, err)
+		}
+		if !re.MatchString(value) {
+			return value, fmt.Errorf("%q does not match %s", excerpt(value), m.Regex)
+		}
+	}
+	return value, nil
+}
+
+func evalJSONPath(expr, out string) (string, error) {
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	j := jsonpath.New("assert")
+	if err := j.Parse(expr); err != nil {
+		return "", fmt.Errorf("jsonPath %s is invalid: %v", expr, err)
+	}
+	var obj interface{}
+	if err := json.Unmarshal([]byte(out), &obj); err != nil {
+		return "", fmt.Errorf("is not json: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := j.Execute(buf, obj); err != nil {
+		return "", fmt.Errorf("jsonPath %s: %v", expr, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// sameAcrossHosts returns an error listing the hosts of every value when the values are not the same.
+func sameAcrossHosts(values map[string]string) error {
+	groups := make(map[string][]string)
+	for host, v := range values {
+		groups[v] = append(groups[v], host)
+	}
+	if len(groups) <= 1 {
+		return nil
+	}
+	lines := make([]string, 0, len(groups))
+	for v, hosts := range groups {
+		sort.Strings(hosts)
+		lines = append(lines, fmt.Sprintf("%s: %q", strings.Join(hosts, ","), excerpt(v)))
+	}
+	sort.Strings(lines)
+	return fmt.Errorf("stdout is not the same across hosts: %s", strings.Join(lines, "; "))
+}
+
+func excerpt(s string) string {
+	const max = 200
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"fmt"
+	osexec "os/exec"
+	"strings"
+	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+func Test_checkAssert(t *testing.T) {
+	one := 1
+	ok := "ok"
+	tests := []struct {
+		name      string
+		assert    v1.Assert
+		code      int
+		stdout    string
+		stderr    string
+		wantValue string
+		wantMsgs  []string
+	}{
+		{
+			name:      "default exit code",
+			assert:    v1.Assert{Command: "true"},
+			stdout:    " ok\n",
+			wantValue: "ok",
+		},
+		{
+			name:     "exit code",
+			assert:   v1.Assert{Command: "false"},
+			code:     1,
+			wantMsgs: []string{"exit code is 1, want 0"},
+		},
+		{
+			name:   "expected exit code",
+			assert: v1.Assert{Command: "false", ExitCode: &one},
+			code:   1,
+		},
+		{
+			name:      "equals",
+			assert:    v1.Assert{Stdout: &v1.OutputMatch{Equals: &ok}},
+			stdout:    "ok\n",
+			wantValue: "ok",
+		},
+		{
+			name:      "contains and stderr regex",
+			assert:    v1.Assert{Stdout: &v1.OutputMatch{Contains: "Ready"}, Stderr: &v1.OutputMatch{Regex: "^$"}},
+			stdout:    "node-0 NotReady",
+			stderr:    "warning",
+			wantValue: "node-0 NotReady",
+			wantMsgs:  []string{`stderr "warning" does not match ^$`},
+		},
+		{
+			name:      "jsonPath",
+			assert:    v1.Assert{Stdout: &v1.OutputMatch{JSONPath: ".items[*].ready", Equals: &ok}},
+			stdout:    `{"items":[{"ready":"no"}]}`,
+			wantValue: "no",
+			wantMsgs:  []string{`stdout is "no", want "ok"`},
+		},
+		{
+			name:      "jsonPath not json",
+			assert:    v1.Assert{Stdout: &v1.OutputMatch{JSONPath: "{.a}"}},
+			stdout:    "a",
+			wantValue: "a",
+			wantMsgs:  []string{"stdout is not json: invalid character 'a' looking for beginning of value"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, msgs := checkAssert(&tt.assert, tt.code, tt.stdout, tt.stderr)
+			if value != tt.wantValue {
+				t.Errorf("checkAssert() value = %q, want %q", value, tt.wantValue)
+			}
+			if strings.Join(msgs, ";") != strings.Join(tt.wantMsgs, ";") {
+				t.Errorf("checkAssert() msgs = %v, want %v", msgs, tt.wantMsgs)
+			}
+		})
+	}
+}
+
+func Test_sameAcrossHosts(t *testing.T) {
+	if err := sameAcrossHosts(map[string]string{"node-0": "a", "node-1": "a"}); err != nil {
+		t.Errorf("sameAcrossHosts() error = %v", err)
+	}
+	err := sameAcrossHosts(map[string]string{"node-0": "a", "node-1": "b", "node-2": "a"})
+	want := `stdout is not the same across hosts: node-0,node-2: "a"; node-1: "b"`
+	if err == nil || err.Error() != want {
+		t.Errorf("sameAcrossHosts() error = %v, want %s", err, want)
+	}
+}
+
+func Test_exitCode(t *testing.T) {
+	err := osexec.Command("sh", "-c", "exit 3").Run()
+	if code, ok := exitCode(fmt.Errorf("run: %w", err)); !ok || code != 3 {
+		t.Errorf("exitCode() = %d, %v, want 3, true", code, ok)
+	}
+	if _, ok := exitCode(fmt.Errorf("dial timeout")); ok {
+		t.Errorf("exitCode() ok = true, want false")
+	}
+}
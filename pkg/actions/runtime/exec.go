@@ -0,0 +1,116 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+const defaultShell = "bash"
+
+// withSpecEnv returns a copy of data whose env is the default env of the spec overridden by the env of data.
+func withSpecEnv(spec v1.ActionSpec, data v1.ActionData) v1.ActionData {
+	if len(spec.Env) == 0 {
+		return data
+	}
+	env := make(map[string]string, len(spec.Env)+len(data.Env))
+	for k, v := range spec.Env {
+		env[k] = v
+	}
+	for k, v := range data.Env {
+		env[k] = v
+	}
+	data.Env = env
+	return data
+}
+
+// execScript renders the script of the exec with the env and workdir of the options.
+func execScript(opts v1.ExecOptions, script string) string {
+	sb := strings.Builder{}
+	keys := make([]string, 0, len(opts.Env))
+	for k := range opts.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("export %s=%s\n", k, envQuote(opts.Env[k])))
+	}
+	if opts.WorkDir != "" {
+		sb.WriteString(fmt.Sprintf("cd %s\n", shellQuote(opts.WorkDir)))
+	}
+	sb.WriteString(script)
+	return sb.String()
+}
+
+// Script is an exec block which is uploaded to the host and run once as a single script.
+type Script struct {
+	// File local path of the rendered script
+	File string
+	// Target remote path of the uploaded script
+	Target string
+	// Command runs the uploaded script by the shell and user of the exec, and removes it
+	Command string
+}
+
+// scriptContent renders the content of the script file, it exits on the first failed command.
+func scriptContent(opts v1.ExecOptions, script string) string {
+	return "set -e\n" + execScript(opts, script)
+}
+
+// scriptCommand returns the command which runs the uploaded script of target and removes it.
+func scriptCommand(opts v1.ExecOptions, target string) string {
+	shell := opts.Shell
+	if shell == "" {
+		shell = defaultShell
+	}
+	run := fmt.Sprintf("%s %s", shell, shellQuote(target))
+	if opts.User != "" {
+		run = fmt.Sprintf("sudo -H -u %s %s", shellQuote(opts.User), run)
+	}
+	if opts.Timeout != nil && opts.Timeout.Duration > 0 {
+		run = fmt.Sprintf("timeout -k 10 %d %s", int(math.Ceil(opts.Timeout.Seconds())), run)
+	}
+	return fmt.Sprintf("%s; rc=$?; rm -f %s; exit $rc", run, shellQuote(target))
+}
+
+// timeoutError returns a clear error when the exec is killed by the timeout of the options,
+// timeout exits with 124 when the command times out.
+func timeoutError(opts v1.ExecOptions, err error) error {
+	if opts.Timeout == nil || opts.Timeout.Duration <= 0 {
+		return err
+	}
+	if code, ok := exitCode(err); ok && code == 124 {
+		return fmt.Errorf("timed out after %s: %w", opts.Timeout.Duration, err)
+	}
+	return err
+}
+
+// shellQuote quotes s by single quotes, nothing in it is expanded by the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// envQuote quotes s by double quotes, variables like $PATH in it are still expanded by the shell.
+func envQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`")
+	return `"` + r.Replace(s) + `"`
+}
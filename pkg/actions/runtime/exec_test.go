@@ -0,0 +1,124 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/labring/sealvm/pkg/ssh"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_scriptContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   v1.ExecOptions
+		script string
+		want   string
+	}{
+		{
+			name:   "default",
+			script: "cd /root\nls -l",
+			want:   "set -e\ncd /root\nls -l",
+		},
+		{
+			name: "env and workdir",
+			opts: v1.ExecOptions{
+				Env:     map[string]string{"PATH": "$PATH:/usr/local/go/bin", "GOPROXY": "https://goproxy.cn"},
+				WorkDir: "/root/sealos",
+			},
+			script: "make build",
+			want:   "set -e\n" + `export GOPROXY="https://goproxy.cn"` + "\n" + `export PATH="$PATH:/usr/local/go/bin"` + "\n" + `cd '/root/sealos'` + "\nmake build",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scriptContent(tt.opts, tt.script); got != tt.want {
+				t.Errorf("scriptContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_scriptCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		opts v1.ExecOptions
+		want string
+	}{
+		{
+			name: "default",
+			want: `bash '/tmp/a.sh'; rc=$?; rm -f '/tmp/a.sh'; exit $rc`,
+		},
+		{
+			name: "user and shell",
+			opts: v1.ExecOptions{
+				User:  "ubuntu",
+				Shell: "sh",
+			},
+			want: `sudo -H -u 'ubuntu' sh '/tmp/a.sh'; rc=$?; rm -f '/tmp/a.sh'; exit $rc`,
+		},
+		{
+			name: "timeout",
+			opts: v1.ExecOptions{
+				User:    "ubuntu",
+				Timeout: &metav1.Duration{Duration: 90500 * time.Millisecond},
+			},
+			want: `timeout -k 10 91 sudo -H -u 'ubuntu' bash '/tmp/a.sh'; rc=$?; rm -f '/tmp/a.sh'; exit $rc`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scriptCommand(tt.opts, "/tmp/a.sh"); got != tt.want {
+				t.Errorf("scriptCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_withSpecEnv(t *testing.T) {
+	spec := v1.ActionSpec{
+		Env: map[string]string{"GOPROXY": "https://goproxy.cn", "GO111MODULE": "on"},
+	}
+	data := v1.ActionData{
+		ExecOptions: v1.ExecOptions{
+			Env: map[string]string{"GOPROXY": "direct"},
+		},
+	}
+	got := withSpecEnv(spec, data)
+	want := map[string]string{"GOPROXY": "direct", "GO111MODULE": "on"}
+	if !reflect.DeepEqual(got.Env, want) {
+		t.Errorf("withSpecEnv() = %v, want %v", got.Env, want)
+	}
+	if len(data.Env) != 1 {
+		t.Errorf("withSpecEnv() modified the env of data: %v", data.Env)
+	}
+}
+
+func Test_checksumReport(t *testing.T) {
+	result := &ssh.SyncResult{Uploaded: []string{"bin/sealos"}, Skipped: []string{"a"}, Deleted: []string{"old"}}
+	want := []string{"uploaded /root/sealos/bin/sealos", "skipped /root/sealos/a", "deleted /root/sealos/old"}
+	if got := checksumReport("/root/sealos", result); !reflect.DeepEqual(got, want) {
+		t.Errorf("checksumReport() = %v, want %v", got, want)
+	}
+	if got := checksumReport("/usr/bin/sealos", &ssh.SyncResult{Skipped: []string{""}}); !reflect.DeepEqual(got, []string{"skipped /usr/bin/sealos"}) {
+		t.Errorf("checksumReport() = %v, want the target", got)
+	}
+}
@@ -17,7 +17,7 @@ limitations under the License.
 package runtime
 
 import (
-	"context"
+	"bytes"
 	"fmt"
 	"github.com/labring/sealvm/pkg/configs"
 	"github.com/labring/sealvm/pkg/ssh"
@@ -25,24 +25,47 @@ import (
 	fileutil "github.com/labring/sealvm/pkg/utils/file"
 	"github.com/labring/sealvm/pkg/utils/logger"
 	v1 "github.com/labring/sealvm/types/api/v1"
-	"golang.org/x/sync/errgroup"
+	"io"
 	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"os"
 	"path"
+	"sync"
+	"time"
 )
 
 type Interface interface {
 	MountOnce(name, src, target string) error
 	UnMountOnce(name, target string) error
-	Copy(names []string, data v1.ActionData) error
-	Exec(names []string, data v1.ActionData) error
+	// CopyOnce copies src to target of the host incrementally, only the files whose size or sha256 differ are uploaded.
+	CopyOnce(name, src, target string, opts ssh.SyncOptions) (*ssh.SyncResult, error)
+	// ExecOnce uploads the script file to the host and runs it once in a single session,
+	// so cd, variables, heredocs and if blocks work across lines and the first failed command stops it.
+	// stdout and stderr of the host are streamed line by line with the host as prefix, and written to stdout and stderr.
+	ExecOnce(name string, script Script, stdout, stderr io.Writer) error
+	// CmdOutput runs the command on the host and returns its combined stdout and stderr.
+	CmdOutput(name, cmd string) ([]byte, error)
 }
 
 type action struct {
 	vm        *v1.VirtualMachine
+	hosts     []string
+	checksum  bool
 	nameAndIp map[string]string
 	client    *ssh.Exec
 	Interface
+
+	// sshClient is the ssh client of the vm, its connections are shared by the runtimes forked from the same one
+	sshClient ssh.Interface
+
+	// vars are the outputs of the steps, shared by the runtimes forked from the same one
+	vars *variables
+
+	// name and step of the running action, every step on every host is recorded into results
+	name    string
+	step    int
+	lock    sync.Mutex
+	results []StepResult
 }
 
 func (m *action) Apply(action *v1.Action) error {
@@ -61,12 +84,60 @@ func (m *action) Apply(action *v1.Action) error {
 		}
 	}()
 	names, nameAndIPs := getNameAndIPs(action, m.vm)
+	if m.hosts != nil {
+		names = sets.NewString(names...).Intersection(sets.NewString(m.hosts...)).List()
+	}
 	m.nameAndIp = nameAndIPs
+	for _, msg := range getUnmatchedOns(action, m.vm) {
+		logger.Warn("action selector matches no host: %s", msg)
+	}
 	if len(names) == 0 {
 		logger.Warn("lookup names is empty")
-		return nil
+		if !hasLocal(action) {
+			action.Status.Phase = v1.ActionPhaseComplete
+			action.Status.Message = "lookup names is empty"
+			return nil
+		}
+	} else {
+		logger.Info("lookup names: %v", nameAndIPs)
+		if err = m.initInterface(names, nameAndIPs); err != nil {
+			return err
+		}
+	}
+	fns := []func(names []string, data v1.ActionData) error{
+		m.Local,
+		m.Mount,
+		m.UnMount,
+		m.Exec,
+		m.Copy,
+		m.CopyContent,
+		m.Template,
+		m.WaitFor,
+		m.Assert,
+	}
+	m.name = action.Name
+	for i, data := range action.Spec.Data {
+		m.step = i
+		if err = validateRegister(data); err != nil {
+			return err
+		}
+		data, err = renderData(withSpecEnv(action.Spec, data), m.vars.context())
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns {
+			fnErr := fn(names, data)
+			if fnErr != nil {
+				err = fnErr
+				return err
+			}
+		}
 	}
-	logger.Info("lookup names: %v", nameAndIPs)
+	action.Status.Phase = v1.ActionPhaseComplete
+	return nil
+}
+
+func (m *action) initInterface(names []string, nameAndIPs map[string]string) error {
 	ips := make([]string, 0)
 	for _, name := range names {
 		if _, ok := nameAndIPs[name]; !ok {
@@ -74,41 +145,31 @@ func (m *action) Apply(action *v1.Action) error {
 		}
 		ips = append(ips, nameAndIPs[name])
 	}
-	var execClient *ssh.Exec
 	defaultProvider, _ := system.Get(system.DefaultProvider)
-	var ii Interface
 	switch defaultProvider {
 	case v1.MultipassType:
-		execClient, err = ssh.NewExecCmdFromIPs(m.vm, ips)
+		execClient, err := ssh.NewExecCmdFromClient(m.vm, m.sshClient, ips)
 		if err != nil {
 			return err
 		}
 		m.client = execClient
-		ii = newMultiPassAction(m.client)
+		m.Interface = newMultiPassAction(m.client, nameAndIPs)
 	case v1.OrbType:
-		ii = newOrbAction()
+		m.Interface = newOrbAction()
 	default:
 		return fmt.Errorf("action not support type: %s", defaultProvider)
 	}
-	m.Interface = ii
-	fns := []func(names []string, data v1.ActionData) error{
-		m.Mount,
-		m.UnMount,
-		m.Exec,
-		m.Copy,
-		m.CopyContent,
-	}
+	return nil
+}
+
+// hasLocal returns true if any step of the action runs on the local host, such actions run without hosts.
+func hasLocal(action *v1.Action) bool {
 	for _, data := range action.Spec.Data {
-		for _, fn := range fns {
-			fnErr := fn(names, data)
-			if fnErr != nil {
-				err = fnErr
-				return err
-			}
+		if data.ActionLocal != "" {
+			return true
 		}
 	}
-	action.Status.Phase = v1.ActionPhaseComplete
-	return nil
+	return false
 }
 
 func (m *action) Mount(names []string, data v1.ActionData) error {
@@ -118,31 +179,129 @@ func (m *action) Mount(names []string, data v1.ActionData) error {
 	if data.ActionMount.Source == "" || data.ActionMount.Target == "" {
 		return fmt.Errorf("mount data is empty source or target")
 	}
-	eg, _ := errgroup.WithContext(context.Background())
-
-	for _, name := range names {
-		name := name
-		eg.Go(func() error {
-			logger.Debug("mount %s %s:%s", data.ActionMount.Source, name, data.ActionMount.Target)
-			return m.MountOnce(name, data.ActionMount.Source, data.ActionMount.Target)
-		})
-	}
-	return eg.Wait()
+	return m.runOnHosts(names, "mount", func(name string, _, _ io.Writer) error {
+		logger.Debug("mount %s %s:%s", data.ActionMount.Source, name, data.ActionMount.Target)
+		return m.MountOnce(name, data.ActionMount.Source, data.ActionMount.Target)
+	})
 }
 func (m *action) UnMount(names []string, data v1.ActionData) error {
 	if data.ActionUmount == "" {
 		return nil
 	}
-	eg, _ := errgroup.WithContext(context.Background())
+	return m.runOnHosts(names, "umount", func(name string, _, _ io.Writer) error {
+		logger.Debug("unmount %s:%s", name, data.ActionUmount)
+		return m.UnMountOnce(name, data.ActionUmount)
+	})
+}
 
-	for _, name := range names {
-		name := name
-		eg.Go(func() error {
-			logger.Debug("unmount %s:%s", name, data.ActionUmount)
-			return m.UnMountOnce(name, data.ActionUmount)
-		})
+func (m *action) Exec(names []string, data v1.ActionData) error {
+	if data.ActionExec == "" {
+		return nil
+	}
+	script, clean, err := newScript(data.ExecOptions, data.ActionExec)
+	if err != nil {
+		return err
+	}
+	defer clean()
+	logger.Debug("names %+v,exec %s", names, data.ActionExec)
+	return m.runOnHosts(names, "exec", func(name string, stdout, stderr io.Writer) error {
+		out := &bytes.Buffer{}
+		if data.Register != "" {
+			stdout = io.MultiWriter(stdout, out)
+		}
+		if err := m.ExecOnce(name, script, stdout, stderr); err != nil {
+			return fmt.Errorf("failed to exec script on %s: %w", name, timeoutError(data.ExecOptions, err))
+		}
+		return m.register(name, names, data, out.Bytes())
+	})
+}
+
+// register saves the captured stdout of the host as the host variable, and as the variable
+// when the host is the first of names, so a step on a single host sets the variable for all later steps.
+func (m *action) register(name string, names []string, data v1.ActionData, out []byte) error {
+	if data.Register == "" {
+		return nil
+	}
+	value, err := parseRegistered(data.RegisterFormat, out)
+	if err != nil {
+		return fmt.Errorf("failed to register %s on %s: %v", data.Register, name, err)
+	}
+	m.vars.setHost(name, data.Register, value)
+	if len(names) > 0 && names[0] == name {
+		m.vars.set(data.Register, value)
+	}
+	logger.Debug("%s: register %s=%v", name, data.Register, value)
+	return nil
+}
+
+// newScript writes the script file of the exec to a temporary directory, clean removes it.
+func newScript(opts v1.ExecOptions, content string) (Script, func(), error) {
+	tmpDir := path.Join(configs.DefaultRootfsDir(), "tmp")
+	_ = os.MkdirAll(tmpDir, 0755)
+	newDir, _ := fileutil.MkTmpdir(tmpDir)
+	clean := func() {
+		_ = os.RemoveAll(newDir)
+	}
+	newFile := path.Join(newDir, "action-exec.sh")
+	if err := fileutil.WriteFile(newFile, []byte(scriptContent(opts, content))); err != nil {
+		clean()
+		return Script{}, nil, fmt.Errorf("failed to write exec script: %v", err)
 	}
-	return eg.Wait()
+	target := fmt.Sprintf("/tmp/sealvm-action-%d.sh", time.Now().UnixNano())
+	return Script{
+		File:    newFile,
+		Target:  target,
+		Command: scriptCommand(opts, target),
+	}, clean, nil
+}
+
+func (m *action) Copy(names []string, data v1.ActionData) error {
+	if data.ActionCopy == nil {
+		return nil
+	}
+	return m.copyToHosts(names, "copy", data.ActionCopy.Source, data.ActionCopy.Target, ssh.SyncOptions{Delete: data.ActionCopy.Sync})
+}
+
+func (m *action) copyToHosts(names []string, stepType, src, target string, opts ssh.SyncOptions) error {
+	if src == "" || target == "" {
+		return fmt.Errorf("copy data is empty source or target")
+	}
+	logger.Debug("names %+v,copy from %s to %s", names, src, target)
+	return m.runOnHosts(names, stepType, func(name string, stdout, _ io.Writer) error {
+		opts := opts
+		opts.Progress = "copying files to " + name
+		result, err := m.CopyOnce(name, src, target, opts)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s to %s:%s: %v", src, name, target, err)
+		}
+		logger.Info("%s: copy %s to %s: %s", name, src, target, result)
+		if m.checksum {
+			for _, line := range checksumReport(target, result) {
+				logger.Info("%s: %s", name, line)
+				_, _ = fmt.Fprintln(stdout, line)
+			}
+		}
+		return nil
+	})
+}
+
+// checksumReport returns a line for every file of the result with the full target path.
+func checksumReport(target string, result *ssh.SyncResult) []string {
+	lines := make([]string, 0)
+	for _, group := range []struct {
+		name  string
+		files []string
+	}{
+		{"uploaded", result.Uploaded},
+		{"chmoded", result.Chmoded},
+		{"skipped", result.Skipped},
+		{"deleted", result.Deleted},
+	} {
+		for _, f := range group.files {
+			lines = append(lines, fmt.Sprintf("%s %s", group.name, path.Join(target, f)))
+		}
+	}
+	return lines
 }
 
 func (m *action) CopyContent(names []string, data v1.ActionData) error {
@@ -161,11 +320,5 @@ func (m *action) CopyContent(names []string, data v1.ActionData) error {
 	newFile := path.Join(newDir, "action-generator.sh")
 	_ = fileutil.WriteFile(newFile, []byte(data.ActionCopyContent.Content))
 	logger.Debug("copy content to %s", data.ActionCopyContent.Target)
-	newData := v1.ActionData{
-		ActionCopy: &v1.SourceAndTarget{
-			Source: newFile,
-			Target: data.ActionCopyContent.Target,
-		},
-	}
-	return m.Copy(names, newData)
+	return m.copyToHosts(names, "copyContent", newFile, data.ActionCopyContent.Target, ssh.SyncOptions{})
 }
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/utils/exec"
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+const (
+	// localHost is the host name of the local steps in the results
+	localHost = "local"
+	// outputEnv is the environment variable of the file which the local steps write their outputs to
+	outputEnv = "SEALVM_OUTPUT"
+)
+
+var outputKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Local runs the exec on the host running sealvm once, whatever hosts the action selects.
+func (m *action) Local(_ []string, data v1.ActionData) error {
+	if data.ActionLocal == "" {
+		return nil
+	}
+	script, clean, err := newScript(data.ExecOptions, data.ActionLocal)
+	if err != nil {
+		return err
+	}
+	defer clean()
+	outputFile := filepath.Join(filepath.Dir(script.File), "output")
+	if err = fileutil.WriteFile(outputFile, nil); err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	logger.Debug("local exec %s", data.ActionLocal)
+	return m.runOnHosts([]string{localHost}, "local", func(name string, stdout, stderr io.Writer) error {
+		ctx := context.Background()
+		if data.Timeout != nil && data.Timeout.Duration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, data.Timeout.Duration)
+			defer cancel()
+		}
+		out := &bytes.Buffer{}
+		if data.Register != "" {
+			stdout = io.MultiWriter(stdout, out)
+		}
+		env := []string{fmt.Sprintf("%s=%s", outputEnv, outputFile)}
+		if err := exec.CmdContextWithPrefix(ctx, name, env, stdout, stderr, defaultShell, "-c", scriptCommand(v1.ExecOptions{User: data.User, Shell: data.Shell}, script.File)); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("failed to exec local script: timed out after %s", data.Timeout.Duration)
+			}
+			return fmt.Errorf("failed to exec local script: %w", err)
+		}
+		content, err := fileutil.ReadAll(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to read outputs: %v", err)
+		}
+		outputs, err := parseOutputs(content)
+		if err != nil {
+			return err
+		}
+		for k, v := range outputs {
+			logger.Info("local: set variable %s=%s", k, v)
+			m.vars.set(k, v)
+		}
+		return m.register(name, []string{name}, data, out.Bytes())
+	})
+}
+
+// parseOutputs parses the key=value lines of the output file, empty lines and lines starting with # are ignored.
+func parseOutputs(content []byte) (map[string]string, error) {
+	outputs := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !outputKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid output at line %d of $%s: %q, want key=value", n, outputEnv, line)
+		}
+		outputs[key] = value
+	}
+	return outputs, scanner.Err()
+}
@@ -21,35 +21,45 @@ import (
 	"github.com/labring/sealvm/pkg/ssh"
 	"github.com/labring/sealvm/pkg/utils/exec"
 	"github.com/labring/sealvm/pkg/utils/logger"
-	v1 "github.com/labring/sealvm/types/api/v1"
+	"io"
 )
 
-func newMultiPassAction(client *ssh.Exec) Interface {
+func newMultiPassAction(client *ssh.Exec, nameAndIp map[string]string) Interface {
 	return &multiPassAction{
-		client: client,
+		client:    client,
+		nameAndIp: nameAndIp,
 	}
 }
 
 type multiPassAction struct {
-	client *ssh.Exec
+	client    *ssh.Exec
+	nameAndIp map[string]string
 }
 
-func (m *multiPassAction) Exec(names []string, data v1.ActionData) error {
-	if data.ActionExec == "" {
-		return nil
+func (m *multiPassAction) ExecOnce(name string, script Script, stdout, stderr io.Writer) error {
+	ip, ok := m.nameAndIp[name]
+	if !ok {
+		return fmt.Errorf("name %s not found", name)
 	}
-	logger.Debug("names %+v,exec %s", names, data.ActionExec)
-	return m.client.RunCmd(data.ActionExec)
+	if err := m.client.RunCopyOnce(ip, script.File, script.Target); err != nil {
+		return err
+	}
+	return m.client.RunCmdStream(ip, script.Command, stdout, stderr)
 }
-func (m *multiPassAction) Copy(names []string, data v1.ActionData) error {
-	if data.ActionCopy == nil {
-		return nil
+func (m *multiPassAction) CmdOutput(name, cmd string) ([]byte, error) {
+	ip, ok := m.nameAndIp[name]
+	if !ok {
+		return nil, fmt.Errorf("name %s not found", name)
 	}
-	if data.ActionCopy.Source == "" || data.ActionCopy.Target == "" {
-		return fmt.Errorf("copy data is empty source or target")
+	return m.client.RunCmdOutput(ip, cmd)
+}
+
+func (m *multiPassAction) CopyOnce(name, src, target string, opts ssh.SyncOptions) (*ssh.SyncResult, error) {
+	ip, ok := m.nameAndIp[name]
+	if !ok {
+		return nil, fmt.Errorf("name %s not found", name)
 	}
-	logger.Debug("names %+v,copy from %s to %s", names, data.ActionCopy.Source, data.ActionCopy.Target)
-	return m.client.RunCopy(data.ActionCopy.Source, data.ActionCopy.Target)
+	return m.client.RunSyncOnce(ip, src, target, opts)
 }
 
 func (m *multiPassAction) MountOnce(name, src, target string) error {
@@ -17,12 +17,14 @@ limitations under the License.
 package runtime
 
 import (
-	"context"
+	"bytes"
 	"fmt"
+	"github.com/labring/sealvm/pkg/ssh"
 	"github.com/labring/sealvm/pkg/utils/exec"
 	"github.com/labring/sealvm/pkg/utils/logger"
-	v1 "github.com/labring/sealvm/types/api/v1"
-	"golang.org/x/sync/errgroup"
+	"io"
+	"os"
+	osexec "os/exec"
 	"strings"
 )
 
@@ -44,47 +46,53 @@ func (m *orbAction) UnMountOnce(name, target string) error {
 	return nil
 }
 
-func (m *orbAction) Exec(names []string, data v1.ActionData) error {
-	if data.ActionExec == "" {
-		return nil
+func (m *orbAction) ExecOnce(name string, script Script, stdout, stderr io.Writer) error {
+	err := exec.Cmd("scp", script.File, fmt.Sprintf("root@%s@orb:%s", name, script.Target))
+	if err != nil {
+		return err
 	}
-	logger.Debug("names %+v,exec %s", names, data.ActionExec)
+	return exec.CmdWithPrefix(name, stdout, stderr, "ssh", fmt.Sprintf("root@%s@orb", name), script.Command)
+}
 
-	for _, name := range names {
-		for _, cmd := range strings.Split(data.ActionExec, "\n") {
-			if strings.TrimSpace(cmd) == "" {
-				continue
-			}
-			err := exec.Cmd("/bin/bash", "-c", fmt.Sprintf("ssh root@%s@orb \"%s\"", name, cmd))
-			if err != nil {
-				return err
-			}
-		}
+func (m *orbAction) CmdOutput(name, cmd string) ([]byte, error) {
+	return exec.CmdOutput("ssh", fmt.Sprintf("root@%s@orb", name), cmd)
+}
 
-	}
-	return nil
+func (m *orbAction) CopyOnce(name, src, target string, opts ssh.SyncOptions) (*ssh.SyncResult, error) {
+	return ssh.Sync(&orbRemote{name: name}, src, target, opts)
 }
-func (m *orbAction) Copy(names []string, data v1.ActionData) error {
-	if data.ActionCopy == nil {
-		return nil
-	}
-	if data.ActionCopy.Source == "" || data.ActionCopy.Target == "" {
-		return fmt.Errorf("copy data is empty source or target")
+
+// orbRemote runs the commands of a sync and uploads the files by ssh.
+type orbRemote struct {
+	name string
+}
+
+func (r *orbRemote) Output(cmd string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	c := osexec.Command("ssh", fmt.Sprintf("root@%s@orb", r.name), cmd)
+	c.Stderr = stderr
+	out, err := c.Output()
+	if err != nil {
+		return out, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
 	}
-	logger.Debug("names %+v,copy from %s to %s", names, data.ActionCopy.Source, data.ActionCopy.Target)
-	eg, _ := errgroup.WithContext(context.Background())
-	for _, name := range names {
-		name := name
-		eg.Go(func() error {
-			err := exec.Cmd("/bin/bash", "-c", fmt.Sprintf("scp %s root@%s@orb:%s", data.ActionCopy.Source, name, data.ActionCopy.Target))
-			if err != nil {
-				return err
-			}
-			return nil
-		})
+	return out, nil
+}
+
+// Upload streams the file to a temporary path by ssh and renames it, so a running binary can be replaced.
+func (r *orbRemote) Upload(localPath, remotePath string, mode os.FileMode, progress io.Writer) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
 	}
-	if err := eg.Wait(); err != nil {
-		return fmt.Errorf("failed to exec command, err: %v", err)
+	defer f.Close()
+	tmp := remotePath + ".sealvm-tmp"
+	stderr := &bytes.Buffer{}
+	c := osexec.Command("ssh", fmt.Sprintf("root@%s@orb", r.name), "cat > "+shellQuote(tmp))
+	c.Stdin = io.TeeReader(f, progress)
+	c.Stderr = stderr
+	if err = c.Run(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
 	}
-	return nil
+	_, err = r.Output(fmt.Sprintf("chmod %o %s && mv -f %s %s", mode, shellQuote(tmp), shellQuote(tmp), shellQuote(remotePath)))
+	return err
 }
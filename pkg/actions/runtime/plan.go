@@ -0,0 +1,230 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/hash"
+	strutil "github.com/labring/sealvm/pkg/utils/strings"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+// HostPlan is the list of rendered steps which would run on a host.
+type HostPlan struct {
+	Name  string
+	IP    string
+	Steps []string
+}
+
+// Plan is the result of resolving an action against the cluster without touching any vm.
+type Plan struct {
+	Hosts     []HostPlan
+	Unmatched []string
+}
+
+// Plan resolves the hosts of the action and renders every step for them.
+func (m *action) Plan(action *v1.Action) *Plan {
+	names, nameAndIPs := getNameAndIPs(action, m.vm)
+	p := &Plan{
+		Unmatched: getUnmatchedOns(action, m.vm),
+	}
+	if len(names) == 0 {
+		p.Unmatched = append(p.Unmatched, "lookup names is empty")
+	}
+	steps := make([]string, 0)
+	for _, data := range action.Spec.Data {
+		steps = append(steps, renderSteps(withSpecEnv(action.Spec, data))...)
+	}
+	for _, name := range names {
+		ip, ok := nameAndIPs[name]
+		if !ok {
+			continue
+		}
+		p.Hosts = append(p.Hosts, HostPlan{
+			Name:  name,
+			IP:    ip,
+			Steps: steps,
+		})
+	}
+	return p
+}
+
+// Print writes the plan in a human-readable form.
+func (p *Plan) Print(w io.Writer) {
+	for _, msg := range p.Unmatched {
+		_, _ = fmt.Fprintf(w, "  [WARN] %s\n", msg)
+	}
+	for _, h := range p.Hosts {
+		_, _ = fmt.Fprintf(w, "  host %s (%s):\n", h.Name, h.IP)
+		for i, step := range h.Steps {
+			_, _ = fmt.Fprintf(w, "    %d. %s\n", i+1, strings.ReplaceAll(step, "\n", "\n       "))
+		}
+	}
+}
+
+func renderSteps(data v1.ActionData) []string {
+	steps := make([]string, 0)
+	if data.ActionLocal != "" {
+		steps = append(steps, renderExec("local", data, data.ActionLocal))
+	}
+	if data.ActionMount != nil {
+		steps = append(steps, fmt.Sprintf("mount %s -> %s", data.ActionMount.Source, data.ActionMount.Target))
+	}
+	if data.ActionUmount != "" {
+		steps = append(steps, fmt.Sprintf("umount %s", data.ActionUmount))
+	}
+	if data.ActionExec != "" {
+		steps = append(steps, renderExec("exec", data, data.ActionExec))
+	}
+	if data.ActionCopy != nil {
+		step := renderCopy(data.ActionCopy.Source, data.ActionCopy.Target)
+		if data.ActionCopy.Sync {
+			step = strings.Replace(step, "copy ", "sync ", 1)
+		}
+		steps = append(steps, step)
+	}
+	if data.ActionCopyContent != nil {
+		content := []byte(data.ActionCopyContent.Content)
+		steps = append(steps, fmt.Sprintf("write %s (%s, sha256:%s)", data.ActionCopyContent.Target,
+			strutil.FormatSize(int64(len(content))), hash.Digest(content)))
+	}
+	if data.ActionTemplate != nil {
+		steps = append(steps, renderTemplateStep(data.ActionTemplate))
+	}
+	if data.ActionWaitFor != nil {
+		p, err := newProbe(data.ActionWaitFor)
+		if err != nil {
+			steps = append(steps, fmt.Sprintf("waitFor (invalid: %v)", err))
+		} else {
+			steps = append(steps, fmt.Sprintf("waitFor %s (timeout %s, interval %s)", p.desc, p.timeout, p.interval))
+		}
+	}
+	if data.ActionAssert != nil {
+		steps = append(steps, renderAssert(data))
+	}
+	return steps
+}
+
+func renderExec(kind string, data v1.ActionData, content string) string {
+	opts := data.ExecOptions
+	header := kind
+	items := make([]string, 0)
+	if opts.User != "" {
+		items = append(items, "user: "+opts.User)
+	}
+	if opts.Shell != "" {
+		items = append(items, "shell: "+opts.Shell)
+	}
+	if opts.Timeout != nil {
+		items = append(items, "timeout: "+opts.Timeout.Duration.String())
+	}
+	if data.Register != "" {
+		format := data.RegisterFormat
+		if format == "" {
+			format = RegisterFormatText
+		}
+		items = append(items, fmt.Sprintf("register: %s as %s", data.Register, format))
+	}
+	if len(items) > 0 {
+		header = fmt.Sprintf("%s (%s)", kind, strings.Join(items, ", "))
+	}
+	script := execScript(opts, strings.TrimSpace(content))
+	return fmt.Sprintf("%s:\n  %s", header, strings.ReplaceAll(script, "\n", "\n  "))
+}
+
+func renderTemplateStep(t *v1.Template) string {
+	source := "inline"
+	if t.Source != "" {
+		source = t.Source
+	}
+	items := make([]string, 0)
+	_, mode, err := parseTemplate(t)
+	if err != nil {
+		items = append(items, fmt.Sprintf("invalid: %v", err))
+	} else {
+		items = append(items, fmt.Sprintf("mode %04o", mode))
+	}
+	if t.Owner != "" {
+		items = append(items, "owner "+t.Owner)
+	}
+	return fmt.Sprintf("template %s -> %s (%s)", source, t.Target, strings.Join(items, ", "))
+}
+
+func renderAssert(data v1.ActionData) string {
+	a := data.ActionAssert
+	want := 0
+	if a.ExitCode != nil {
+		want = *a.ExitCode
+	}
+	expects := []string{fmt.Sprintf("exit code %d", want)}
+	for _, o := range []struct {
+		name  string
+		match *v1.OutputMatch
+	}{{"stdout", a.Stdout}, {"stderr", a.Stderr}} {
+		if o.match == nil {
+			continue
+		}
+		prefix := o.name
+		if o.match.JSONPath != "" {
+			prefix = fmt.Sprintf("%s %s", o.name, o.match.JSONPath)
+		}
+		if o.match.Equals != nil {
+			expects = append(expects, fmt.Sprintf("%s equals %q", prefix, *o.match.Equals))
+		}
+		if o.match.Contains != "" {
+			expects = append(expects, fmt.Sprintf("%s contains %q", prefix, o.match.Contains))
+		}
+		if o.match.Regex != "" {
+			expects = append(expects, fmt.Sprintf("%s matches %s", prefix, o.match.Regex))
+		}
+	}
+	if a.SameAcrossHosts {
+		expects = append(expects, "stdout same across hosts")
+	}
+	script := execScript(data.ExecOptions, strings.TrimSpace(a.Command))
+	return fmt.Sprintf("assert (%s):\n  %s", strings.Join(expects, ", "), strings.ReplaceAll(script, "\n", "\n  "))
+}
+
+func renderCopy(src, target string) string {
+	f, err := os.Stat(src)
+	if err != nil {
+		return fmt.Sprintf("copy %s -> %s (source error: %v)", src, target, err)
+	}
+	if !f.IsDir() {
+		return fmt.Sprintf("copy %s -> %s (%s, sha256:%s)", src, target, strutil.FormatSize(f.Size()), hash.FileDigest(src))
+	}
+	files, err := fileutil.GetFiles(src)
+	if err != nil {
+		return fmt.Sprintf("copy %s -> %s (source error: %v)", src, target, err)
+	}
+	size, _ := fileutil.GetFilesSize(files)
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("copy %s -> %s (%d files, %s)", src, target, len(files), strutil.FormatSize(size)))
+	for _, file := range files {
+		rel, _ := filepath.Rel(src, file)
+		fileSize, _ := fileutil.GetFileSize(file)
+		sb.WriteString(fmt.Sprintf("\n  %s (%s, sha256:%s)", path.Join(target, filepath.ToSlash(rel)), strutil.FormatSize(fileSize), hash.FileDigest(file)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// excerptSize is the max size of stdout and stderr kept in a StepResult.
+const excerptSize = 4096
+
+// StepResult is the outcome of a step of an action on a host.
+type StepResult struct {
+	Action   string
+	Step     int
+	Type     string
+	Host     string
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
+	Error    string
+}
+
+// Results returns the results of all steps applied by the runtime.
+func (m *action) Results() []StepResult {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]StepResult(nil), m.results...)
+}
+
+func (m *action) record(result StepResult) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.results = append(m.results, result)
+}
+
+// runOnHosts runs fn of the current step on every host concurrently, and records the result of each host.
+func (m *action) runOnHosts(names []string, stepType string, fn func(name string, stdout, stderr io.Writer) error) error {
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, name := range names {
+		name := name
+		eg.Go(func() error {
+			stdout, stderr := &tailBuffer{max: excerptSize}, &tailBuffer{max: excerptSize}
+			start := time.Now()
+			err := fn(name, stdout, stderr)
+			result := StepResult{
+				Action:   m.name,
+				Step:     m.step,
+				Type:     stepType,
+				Host:     name,
+				Duration: time.Since(start),
+				Stdout:   stdout.String(),
+				Stderr:   stderr.String(),
+			}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			m.record(result)
+			return err
+		})
+	}
+	return eg.Wait()
+}
+
+// tailBuffer keeps the last max bytes written to it as the excerpt of an output.
+type tailBuffer struct {
+	max       int
+	buf       []byte
+	truncated bool
+	lock      sync.Mutex
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.truncated {
+		return "...\n" + string(b.buf)
+	}
+	return string(b.buf)
+}
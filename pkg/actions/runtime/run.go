@@ -0,0 +1,181 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"sync"
+	"time"
+
+	"github.com/labring/sealvm/pkg/process"
+	"github.com/labring/sealvm/pkg/system"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+// RunOptions are the options of running a command on hosts without an action file.
+type RunOptions struct {
+	// Parallel max number of hosts running the command at the same time, 0 is no limit
+	Parallel int
+	// Quiet does not print the output lines of the hosts
+	Quiet bool
+}
+
+// RunResult is the result of a command on a host.
+type RunResult struct {
+	Host string `json:"host"`
+	// ExitCode of the command, it is -1 when the command did not run or its exit code is unknown
+	ExitCode int    `json:"exitCode"`
+	Duration string `json:"duration"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	// Error why the command did not run, it is empty when the command exits with a non-zero code
+	Error string `json:"error,omitempty"`
+}
+
+// Targets returns the names of the hosts of the roles and of the hosts like the ons of an action,
+// a host is a host name, a role for its first host or role:index. All the hosts are returned when both are empty.
+func (m *action) Targets(roles, hosts []string) ([]string, error) {
+	ons := make([]v1.ActionOn, 0)
+	for _, role := range roles {
+		ons = append(ons, v1.ActionOn{Role: role})
+	}
+	for _, h := range hosts {
+		status, err := process.ResolveHost(m.vm, h)
+		if err != nil {
+			return nil, err
+		}
+		ons = append(ons, v1.ActionOn{Role: status.Role, Indexes: []int32{int32(status.Index)}})
+	}
+	if len(ons) == 0 {
+		for _, role := range m.vm.GetRoles() {
+			ons = append(ons, v1.ActionOn{Role: role})
+		}
+	}
+	action := &v1.Action{Spec: v1.ActionSpec{Ons: ons}}
+	if err := m.Validate(action); err != nil {
+		return nil, err
+	}
+	names, nameAndIPs := getNameAndIPs(action, m.vm)
+	m.nameAndIp = nameAndIPs
+	return names, nil
+}
+
+// Run runs the command on the hosts of names found by Targets, the results are in the order of names.
+// The output lines are printed with the host as prefix unless Quiet is set, the runtime must be created
+// by NewRunAction so its ssh client does not print them again.
+func (m *action) Run(names []string, cmd string, opts RunOptions) []RunResult {
+	provider, _ := system.Get(system.DefaultProvider)
+	results := make([]RunResult, len(names))
+	eg, _ := errgroup.WithContext(context.Background())
+	if opts.Parallel > 0 {
+		eg.SetLimit(opts.Parallel)
+	}
+	var printLock sync.Mutex
+	for i, name := range names {
+		i, name := i, name
+		eg.Go(func() error {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			var outWriter, errWriter io.Writer = stdout, stderr
+			outPrinter := &linePrinter{prefix: name, out: os.Stdout, lock: &printLock}
+			errPrinter := &linePrinter{prefix: name, out: os.Stderr, lock: &printLock}
+			if !opts.Quiet {
+				outWriter, errWriter = io.MultiWriter(stdout, outPrinter), io.MultiWriter(stderr, errPrinter)
+			}
+			start := time.Now()
+			err := m.runCmd(provider, name, cmd, outWriter, errWriter)
+			outPrinter.Flush()
+			errPrinter.Flush()
+			result := RunResult{
+				Host:     name,
+				Duration: time.Since(start).Round(time.Millisecond).String(),
+				Stdout:   stdout.String(),
+				Stderr:   stderr.String(),
+			}
+			code, ok := exitCode(err)
+			if ok {
+				result.ExitCode = code
+			} else {
+				result.ExitCode = -1
+				result.Error = err.Error()
+			}
+			results[i] = result
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	return results
+}
+
+// runCmd runs the command on the host in a single session by the provider.
+func (m *action) runCmd(provider, name, cmd string, stdout, stderr io.Writer) error {
+	switch provider {
+	case v1.MultipassType:
+		ip, ok := m.nameAndIp[name]
+		if !ok {
+			return fmt.Errorf("host %s has no ip", name)
+		}
+		return m.sshClient.CmdStream(ip, cmd, stdout, stderr)
+	case v1.OrbType:
+		c := osexec.Command("ssh", fmt.Sprintf("root@%s@orb", name), cmd)
+		c.Stdout = stdout
+		c.Stderr = stderr
+		return c.Run()
+	default:
+		return fmt.Errorf("exec not support type: %s", provider)
+	}
+}
+
+// linePrinter prints every line written to it with the prefix, like the output of the exec steps.
+type linePrinter struct {
+	prefix string
+	out    io.Writer
+	lock   *sync.Mutex
+	buf    []byte
+}
+
+func (p *linePrinter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.print(p.buf[:i])
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush prints the last line which does not end with a newline.
+func (p *linePrinter) Flush() {
+	if len(p.buf) > 0 {
+		p.print(p.buf)
+		p.buf = nil
+	}
+}
+
+func (p *linePrinter) print(line []byte) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, _ = fmt.Fprintf(p.out, "%s: %s\n", p.prefix, line)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAction_Targets(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   []string
+		hosts   []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "all hosts",
+			want: []string{"default-master-0", "default-node-0", "default-node-1"},
+		},
+		{
+			name:  "role",
+			roles: []string{"node"},
+			want:  []string{"default-node-0", "default-node-1"},
+		},
+		{
+			name:  "hosts by name, role and index",
+			hosts: []string{"default-node-1", "master", "node:1"},
+			want:  []string{"default-master-0", "default-node-1"},
+		},
+		{
+			name:    "unknown role",
+			roles:   []string{"worker"},
+			wantErr: "role worker is not defined in cluster default",
+		},
+		{
+			name:    "unknown host",
+			hosts:   []string{"node:5"},
+			wantErr: "role node has no host of index 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &action{vm: newTestVM()}
+			got, err := m.Targets(tt.roles, tt.hosts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Targets() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Targets() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Targets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_linePrinter(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := &linePrinter{prefix: "default-node-0", out: out, lock: &sync.Mutex{}}
+	_, _ = p.Write([]byte("a\nb"))
+	_, _ = p.Write([]byte("c\n\nd"))
+	p.Flush()
+	want := "default-node-0: a\ndefault-node-0: bc\ndefault-node-0: \ndefault-node-0: d\n"
+	if out.String() != want {
+		t.Errorf("linePrinter printed %q, want %q", out.String(), want)
+	}
+}
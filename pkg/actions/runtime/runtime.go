@@ -18,10 +18,15 @@ package runtime
 
 import (
 	"errors"
+	"fmt"
 	"github.com/labring/sealvm/pkg/process"
+	"github.com/labring/sealvm/pkg/ssh"
 	"github.com/labring/sealvm/pkg/utils/logger"
 	"github.com/labring/sealvm/pkg/utils/strings"
 	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -35,6 +40,15 @@ func getNameAndIPs(action *v1.Action, vm *v1.VirtualMachine) ([]string, map[stri
 	}()
 	names := sets.NewString()
 	for _, on := range action.Spec.Ons {
+		if on.Selector != nil {
+			selected, err := selectHosts(on, vm)
+			if err != nil {
+				logger.Warn("invalid selector of role %s: %v", on.Role, err)
+				continue
+			}
+			names.Insert(selected...)
+			continue
+		}
 		if len(on.Indexes) == 0 {
 			h := vm.GetHostByRole(on.Role)
 			if h != nil {
@@ -65,14 +79,170 @@ func getNameAndIPs(action *v1.Action, vm *v1.VirtualMachine) ([]string, map[stri
 	return names.List(), data
 }
 
+// getUnmatchedOns returns a message for every selector in action.Spec.Ons
+// which does not resolve to a running host with an IP.
+func getUnmatchedOns(action *v1.Action, vm *v1.VirtualMachine) []string {
+	if action == nil {
+		return nil
+	}
+	unmatched := make([]string, 0)
+	for _, on := range action.Spec.Ons {
+		if on.Selector != nil {
+			selected, err := selectHosts(on, vm)
+			if err != nil {
+				unmatched = append(unmatched, fmt.Sprintf("selector %s is invalid: %v", metav1.FormatLabelSelector(on.Selector), err))
+				continue
+			}
+			if len(selected) == 0 {
+				unmatched = append(unmatched, fmt.Sprintf("selector %s matches no host", metav1.FormatLabelSelector(on.Selector)))
+			}
+			continue
+		}
+		h := vm.GetHostByRole(on.Role)
+		if h == nil {
+			unmatched = append(unmatched, fmt.Sprintf("role %s is not defined in cluster %s", on.Role, vm.Name))
+			continue
+		}
+		indexes := on.Indexes
+		if len(indexes) == 0 {
+			if h.Count == 0 {
+				unmatched = append(unmatched, fmt.Sprintf("role %s has no hosts", on.Role))
+				continue
+			}
+			for i := 0; i < h.Count; i++ {
+				indexes = append(indexes, int32(i))
+			}
+		}
+		for _, i := range indexes {
+			name := strings.GetID(vm.Name, on.Role, int(i))
+			if int(i) < 0 || int(i) >= h.Count {
+				unmatched = append(unmatched, fmt.Sprintf("role %s index %d is out of range, role %s has %d hosts", on.Role, i, on.Role, h.Count))
+				continue
+			}
+			status := vm.GetHostStatusByName(name)
+			if status == nil {
+				unmatched = append(unmatched, fmt.Sprintf("host %s is not found in cluster status", name))
+				continue
+			}
+			if len(status.IPs) == 0 {
+				unmatched = append(unmatched, fmt.Sprintf("host %s has no IP", name))
+			}
+		}
+	}
+	return unmatched
+}
+
+// Validate returns an error for every on of the action which can never match a host of the cluster:
+// the role is not defined, the index is out of range or the selector is invalid.
+// Ons which match no running host now are only warned when the action is applied.
+func (m *action) Validate(action *v1.Action) error {
+	errArr := make([]error, 0)
+	for i, on := range action.Spec.Ons {
+		if on.Selector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(on.Selector); err != nil {
+				errArr = append(errArr, fmt.Errorf("spec.ons[%d]: selector is invalid: %v", i, err))
+			}
+			if on.Role == "" {
+				continue
+			}
+		}
+		h := m.vm.GetHostByRole(on.Role)
+		if h == nil {
+			errArr = append(errArr, fmt.Errorf("spec.ons[%d]: role %s is not defined in cluster %s", i, on.Role, m.vm.Name))
+			continue
+		}
+		for _, index := range on.Indexes {
+			if int(index) < 0 || int(index) >= h.Count {
+				errArr = append(errArr, fmt.Errorf("spec.ons[%d]: role %s index %d is out of range, role %s has %d hosts", i, on.Role, index, on.Role, h.Count))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errArr)
+}
+
+// selectHosts returns the hosts in the status whose labels match the selector of on,
+// the role and indexes of on narrow the hosts when they are set.
+func selectHosts(on v1.ActionOn, vm *v1.VirtualMachine) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(on.Selector)
+	if err != nil {
+		return nil, err
+	}
+	indexes := sets.NewInt32(on.Indexes...)
+	names := make([]string, 0)
+	for i := range vm.Status.Hosts {
+		host := &vm.Status.Hosts[i]
+		if on.Role != "" && host.Role != on.Role {
+			continue
+		}
+		if indexes.Len() != 0 && !indexes.Has(int32(host.Index)) {
+			continue
+		}
+		if selector.Matches(labels.Set(vm.GetHostLabels(host))) {
+			names = append(names, host.ID)
+		}
+	}
+	return names, nil
+}
+
 func NewAction(name string) (*action, error) {
+	vm, err := loadVM(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewActionFromVM(vm), nil
+}
+
+// NewRunAction returns the action runtime of Run, its ssh client does not print the output lines
+// of the hosts, Run prints them by itself.
+func NewRunAction(name string) (*action, error) {
+	vm, err := loadVM(name)
+	if err != nil {
+		return nil, err
+	}
+	return newAction(vm, false), nil
+}
+
+func loadVM(name string) (*v1.VirtualMachine, error) {
 	i, err := process.NewInterfaceFromName(name)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
-	if i.VMInfo() != nil {
-		return &action{vm: i.VMInfo()}, nil
+	if i.VMInfo() == nil {
+		return nil, errors.New("load vm config error")
 	}
-	return nil, errors.New("load vm config error")
+	return i.VMInfo(), nil
+}
+
+// NewActionFromVM returns the action runtime of the vm object, it is used where the vm is not saved yet.
+func NewActionFromVM(vm *v1.VirtualMachine) *action {
+	return newAction(vm, true)
+}
+
+func newAction(vm *v1.VirtualMachine, isStdout bool) *action {
+	return &action{vm: vm, vars: newVariables(), sshClient: ssh.NewSSHClient(vm, isStdout)}
+}
+
+// WithHosts limits the runtime to the hosts, the actions only run on the hosts selected by both Ons and them.
+func (m *action) WithHosts(hosts []string) *action {
+	m.hosts = hosts
+	return m
+}
+
+// WithChecksum reports every file of the copy steps as uploaded, skipped or deleted.
+func (m *action) WithChecksum(checksum bool) *action {
+	m.checksum = checksum
+	return m
+}
+
+// Fork returns a new runtime of the same vm, a runtime applies one action at a time
+// so every concurrent action needs its own. The variables are shared with the new runtime.
+func (m *action) Fork() *action {
+	return &action{vm: m.vm, hosts: m.hosts, checksum: m.checksum, vars: m.vars, sshClient: m.sshClient}
+}
+
+// Close closes the ssh connections of the runtime and the runtimes forked from it, it is called
+// after all the actions are applied.
+func (m *action) Close() error {
+	return m.sshClient.Close()
 }
@@ -0,0 +1,238 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestVM() *v1.VirtualMachine {
+	return &v1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+		},
+		Spec: v1.VirtualMachineSpec{
+			Hosts: []v1.Host{
+				{
+					Role:  "master",
+					Count: 1,
+				},
+				{
+					Role:  "node",
+					Count: 2,
+				},
+			},
+		},
+		Status: v1.VirtualMachineStatus{
+			Hosts: []v1.VirtualMachineHostStatus{
+				{
+					ID:    "default-master-0",
+					Role:  "master",
+					IPs:   []string{"192.168.64.2"},
+					Index: 0,
+				},
+				{
+					ID:    "default-node-0",
+					Role:  "node",
+					IPs:   []string{"192.168.64.3"},
+					Index: 0,
+				},
+				{
+					ID:    "default-node-1",
+					Role:  "node",
+					Index: 1,
+				},
+			},
+		},
+	}
+}
+
+func Test_getUnmatchedOns(t *testing.T) {
+	tests := []struct {
+		name string
+		ons  []v1.ActionOn
+		want []string
+	}{
+		{
+			name: "all matched",
+			ons: []v1.ActionOn{
+				{Role: "master"},
+				{Role: "node", Indexes: []int32{0}},
+			},
+			want: []string{},
+		},
+		{
+			name: "unknown role",
+			ons: []v1.ActionOn{
+				{Role: "worker"},
+			},
+			want: []string{"role worker is not defined in cluster default"},
+		},
+		{
+			name: "index out of range and no ip",
+			ons: []v1.ActionOn{
+				{Role: "node", Indexes: []int32{1, 2}},
+			},
+			want: []string{
+				"host default-node-1 has no IP",
+				"role node index 2 is out of range, role node has 2 hosts",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := &v1.Action{Spec: v1.ActionSpec{Ons: tt.ons}}
+			if got := getUnmatchedOns(action, newTestVM()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getUnmatchedOns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAction_Plan(t *testing.T) {
+	m := &action{vm: newTestVM()}
+	action := &v1.Action{
+		Spec: v1.ActionSpec{
+			Ons: []v1.ActionOn{{Role: "node"}},
+			Data: []v1.ActionData{
+				{ActionExec: "ls -l /"},
+				{ActionCopyContent: &v1.ContentAndTarget{Content: "abc", Target: "/root/a.sh"}},
+			},
+		},
+	}
+	p := m.Plan(action)
+	if len(p.Hosts) != 1 || p.Hosts[0].Name != "default-node-0" {
+		t.Errorf("Plan() hosts = %+v, want only default-node-0", p.Hosts)
+		return
+	}
+	want := []string{
+		"exec:\n  ls -l /",
+		"write /root/a.sh (3.00B, sha256:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad)",
+	}
+	if !reflect.DeepEqual(p.Hosts[0].Steps, want) {
+		t.Errorf("Plan() steps = %v, want %v", p.Hosts[0].Steps, want)
+	}
+	if len(p.Unmatched) != 1 {
+		t.Errorf("Plan() unmatched = %v, want 1 message", p.Unmatched)
+	}
+}
+
+func Test_getNameAndIPsSelector(t *testing.T) {
+	vm := newTestVM()
+	vm.Spec.Hosts[1].Labels = map[string]string{"zone": "a"}
+	vm.Status.Hosts[1].Labels = map[string]string{"etcd": "true"}
+	tests := []struct {
+		name string
+		on   v1.ActionOn
+		want []string
+	}{
+		{
+			name: "role labels",
+			on:   v1.ActionOn{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}}},
+			want: []string{"default-node-0", "default-node-1"},
+		},
+		{
+			name: "host labels",
+			on:   v1.ActionOn{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"etcd": "true"}}},
+			want: []string{"default-node-0"},
+		},
+		{
+			name: "expressions with indexes",
+			on: v1.ActionOn{
+				Indexes: []int32{1},
+				Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "etcd", Operator: metav1.LabelSelectorOpDoesNotExist},
+				}},
+			},
+			want: []string{"default-node-1"},
+		},
+		{
+			name: "no match",
+			on:   v1.ActionOn{Role: "master", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}}},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := &v1.Action{Spec: v1.ActionSpec{Ons: []v1.ActionOn{tt.on}}}
+			if got, _ := getNameAndIPs(action, vm); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getNameAndIPs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAction_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ons     []v1.ActionOn
+		wantErr []string
+	}{
+		{
+			name: "valid",
+			ons: []v1.ActionOn{
+				{Role: "master"},
+				{Role: "node", Indexes: []int32{0, 1}},
+				{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "b"}}},
+			},
+		},
+		{
+			name: "unknown role and index out of range",
+			ons: []v1.ActionOn{
+				{Role: "worker"},
+				{Role: "node", Indexes: []int32{-1, 2}},
+			},
+			wantErr: []string{
+				"spec.ons[0]: role worker is not defined in cluster default",
+				"spec.ons[1]: role node index -1 is out of range, role node has 2 hosts",
+				"spec.ons[1]: role node index 2 is out of range, role node has 2 hosts",
+			},
+		},
+		{
+			name: "invalid selector",
+			ons: []v1.ActionOn{
+				{Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "zone", Operator: "Near"}}}},
+			},
+			wantErr: []string{`spec.ons[0]: selector is invalid: "Near" is not a valid pod selector operator`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &action{vm: newTestVM()}
+			err := m.Validate(&v1.Action{Spec: v1.ActionSpec{Ons: tt.ons}})
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %s", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	gotemplate "text/template"
+
+	"github.com/labring/sealvm/pkg/configs"
+	"github.com/labring/sealvm/pkg/ssh"
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/labring/sealvm/pkg/utils/template"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+const defaultTemplateMode = 0644
+
+func (m *action) Template(names []string, data v1.ActionData) error {
+	t := data.ActionTemplate
+	if t == nil {
+		return nil
+	}
+	tpl, mode, err := parseTemplate(t)
+	if err != nil {
+		return err
+	}
+	tmpDir := path.Join(configs.DefaultRootfsDir(), "tmp")
+	_ = os.MkdirAll(tmpDir, 0755)
+	newDir, _ := fileutil.MkTmpdir(tmpDir)
+	defer func() {
+		_ = os.RemoveAll(newDir)
+	}()
+	return m.runOnHosts(names, "template", func(name string, stdout, _ io.Writer) error {
+		content, err := renderTemplate(tpl, templateContext(m.vm, name, m.vars.context()))
+		if err != nil {
+			return fmt.Errorf("failed to render template for %s: %v", name, err)
+		}
+		newFile := path.Join(newDir, name)
+		if err = fileutil.WriteFile(newFile, content); err != nil {
+			return err
+		}
+		if err = os.Chmod(newFile, mode); err != nil {
+			return err
+		}
+		result, err := m.CopyOnce(name, newFile, t.Target, ssh.SyncOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to write template to %s:%s: %v", name, t.Target, err)
+		}
+		logger.Info("%s: template %s: %s", name, t.Target, result)
+		if m.checksum {
+			for _, line := range checksumReport(t.Target, result) {
+				_, _ = fmt.Fprintln(stdout, line)
+			}
+		}
+		if t.Owner != "" {
+			if out, err := m.CmdOutput(name, fmt.Sprintf("chown %s %s", shellQuote(t.Owner), shellQuote(t.Target))); err != nil {
+				return fmt.Errorf("failed to chown %s:%s: %v: %s", name, t.Target, err, out)
+			}
+		}
+		return nil
+	})
+}
+
+// parseTemplate parses the template of the step and its mode, the template fails on missing keys.
+func parseTemplate(t *v1.Template) (*gotemplate.Template, os.FileMode, error) {
+	if t.Target == "" {
+		return nil, 0, fmt.Errorf("template target is empty")
+	}
+	if (t.Source == "") == (t.Content == "") {
+		return nil, 0, fmt.Errorf("template must set exactly one of source and content")
+	}
+	mode := os.FileMode(defaultTemplateMode)
+	if t.Mode != "" {
+		n, err := strconv.ParseUint(t.Mode, 8, 32)
+		if err != nil || n > 07777 {
+			return nil, 0, fmt.Errorf("template mode %s is invalid, it must be octal like 0644", t.Mode)
+		}
+		mode = os.FileMode(n)
+	}
+	text, name := t.Content, "content"
+	if t.Source != "" {
+		data, err := fileutil.ReadAll(t.Source)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read template %s: %v", t.Source, err)
+		}
+		text, name = string(data), path.Base(t.Source)
+	}
+	tpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse template %s: %v", name, err)
+	}
+	return tpl, mode, nil
+}
+
+func renderTemplate(tpl *gotemplate.Template, ctx map[string]interface{}) ([]byte, error) {
+	out := &bytes.Buffer{}
+	if err := tpl.Execute(out, ctx); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// hostFacts are the facts of a host in the templates.
+func hostFacts(vm *v1.VirtualMachine, status *v1.VirtualMachineHostStatus) map[string]interface{} {
+	ip := ""
+	if len(status.IPs) > 0 {
+		ip = status.IPs[0]
+	}
+	return map[string]interface{}{
+		"name":     status.ID,
+		"role":     status.Role,
+		"index":    status.Index,
+		"ip":       ip,
+		"ips":      status.IPs,
+		"state":    status.State,
+		"image":    status.ImageName,
+		"capacity": status.Capacity,
+		"labels":   vm.GetHostLabels(status),
+	}
+}
+
+// templateContext returns the context of the templates rendered for the host:
+// .host the facts of the host, .hosts all the hosts ordered by role and index, .roles the hosts by role,
+// .cluster the name of the cluster, .vars and .hostVars the variables of the steps.
+func templateContext(vm *v1.VirtualMachine, name string, vars map[string]interface{}) map[string]interface{} {
+	statuses := make([]*v1.VirtualMachineHostStatus, 0, len(vm.Status.Hosts))
+	for i := range vm.Status.Hosts {
+		statuses = append(statuses, &vm.Status.Hosts[i])
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].Role != statuses[j].Role {
+			return statuses[i].Role < statuses[j].Role
+		}
+		return statuses[i].Index < statuses[j].Index
+	})
+	host := map[string]interface{}{"name": name}
+	hosts := make([]interface{}, 0, len(statuses))
+	roles := make(map[string]interface{})
+	for _, status := range statuses {
+		facts := hostFacts(vm, status)
+		if status.ID == name {
+			host = facts
+		}
+		hosts = append(hosts, facts)
+		list, _ := roles[status.Role].([]interface{})
+		roles[status.Role] = append(list, facts)
+	}
+	ctx := map[string]interface{}{
+		"host":    host,
+		"hosts":   hosts,
+		"roles":   roles,
+		"cluster": map[string]interface{}{"name": vm.Name},
+	}
+	for k, v := range vars {
+		ctx[k] = v
+	}
+	return ctx
+}
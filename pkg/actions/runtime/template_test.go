@@ -0,0 +1,94 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"os"
+	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+func Test_renderTemplate(t *testing.T) {
+	vm := newTestVM()
+	vars := newVariables()
+	vars.set("token", "abc")
+	tests := []struct {
+		name     string
+		template v1.Template
+		host     string
+		want     string
+		wantMode os.FileMode
+		wantErr  bool
+	}{
+		{
+			name:     "host facts",
+			template: v1.Template{Content: `KUBELET_EXTRA_ARGS=--node-ip={{ .host.ip }} --hostname-override={{ .host.name | upper }}`, Target: "/etc/default/kubelet"},
+			host:     "default-node-0",
+			want:     "KUBELET_EXTRA_ARGS=--node-ip=192.168.64.3 --hostname-override=DEFAULT-NODE-0",
+			wantMode: 0644,
+		},
+		{
+			name: "inventory",
+			template: v1.Template{
+				Content: `{{ range $i, $h := .roles.master }}{{ if $i }},{{ end }}{{ $h.name }}=https://{{ $h.ip }}:2380{{ end }} {{ len .hosts }} {{ .cluster.name }} {{ .vars.token }}`,
+				Target:  "/etc/etcd.env",
+				Mode:    "0600",
+			},
+			host:     "default-master-0",
+			want:     "default-master-0=https://192.168.64.2:2380 3 default abc",
+			wantMode: 0600,
+		},
+		{
+			name:     "missing key",
+			template: v1.Template{Content: `{{ .host.hostname }}`, Target: "/etc/hostname"},
+			host:     "default-node-0",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid mode",
+			template: v1.Template{Content: "a", Target: "/etc/a", Mode: "rw"},
+			wantErr:  true,
+		},
+		{
+			name:     "source and content",
+			template: v1.Template{Source: "a.tpl", Content: "a", Target: "/etc/a"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, mode, err := parseTemplate(&tt.template)
+			var got []byte
+			if err == nil {
+				got, err = renderTemplate(tpl, templateContext(vm, tt.host, vars.context()))
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("renderTemplate() = %s, want %s", got, tt.want)
+			}
+			if mode != tt.wantMode {
+				t.Errorf("parseTemplate() mode = %o, want %o", mode, tt.wantMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labring/sealvm/pkg/utils/template"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+const (
+	RegisterFormatText = "text"
+	RegisterFormatJSON = "json"
+)
+
+// variables are the outputs of the steps, they are shared by all the actions of a workflow
+// and referenced in the later steps as ${{ .vars.name }} or ${{ index .hostVars "host" "name" }}.
+type variables struct {
+	lock       sync.RWMutex
+	values     map[string]interface{}
+	hostValues map[string]map[string]interface{}
+}
+
+func newVariables() *variables {
+	return &variables{
+		values:     make(map[string]interface{}),
+		hostValues: make(map[string]map[string]interface{}),
+	}
+}
+
+func (v *variables) set(key string, value interface{}) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.values[key] = value
+}
+
+func (v *variables) setHost(host, key string, value interface{}) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.hostValues[host] == nil {
+		v.hostValues[host] = make(map[string]interface{})
+	}
+	v.hostValues[host][key] = value
+}
+
+// context returns the templating context of the variables.
+func (v *variables) context() map[string]interface{} {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	values := make(map[string]interface{}, len(v.values))
+	for k, val := range v.values {
+		values[k] = val
+	}
+	hostValues := make(map[string]interface{}, len(v.hostValues))
+	for host, hv := range v.hostValues {
+		m := make(map[string]interface{}, len(hv))
+		for k, val := range hv {
+			m[k] = val
+		}
+		hostValues[host] = m
+	}
+	return map[string]interface{}{"vars": values, "hostVars": hostValues}
+}
+
+// parseRegistered parses the captured stdout of a step in the format.
+func parseRegistered(format string, out []byte) (interface{}, error) {
+	switch format {
+	case "", RegisterFormatText:
+		return strings.TrimSpace(string(out)), nil
+	case RegisterFormatJSON:
+		var value interface{}
+		if err := json.Unmarshal(out, &value); err != nil {
+			return nil, fmt.Errorf("stdout is not json: %v", err)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("registerFormat %s is not supported, only %s and %s", format, RegisterFormatText, RegisterFormatJSON)
+	}
+}
+
+// validateRegister checks the register options of data before the step runs.
+func validateRegister(data v1.ActionData) error {
+	if data.Register == "" {
+		if data.RegisterFormat != "" {
+			return fmt.Errorf("registerFormat is set without register")
+		}
+		return nil
+	}
+	if !outputKeyRegex.MatchString(data.Register) {
+		return fmt.Errorf("register %s is invalid, it must be letters, digits and underscores", data.Register)
+	}
+	if data.ActionExec == "" && data.ActionLocal == "" {
+		return fmt.Errorf("register %s can only be used with exec or local", data.Register)
+	}
+	_, err := parseRegistered(data.RegisterFormat, []byte("null"))
+	return err
+}
+
+const (
+	varsLeftDelim  = "${{"
+	varsRightDelim = "}}"
+)
+
+var missingKeyRegex = regexp.MustCompile(`at <([^>]*)>: map has no entry for key`)
+
+// renderData renders the ${{ }} templates in all the strings of data with the context.
+func renderData(data v1.ActionData, ctx map[string]interface{}) (v1.ActionData, error) {
+	out := v1.ActionData{}
+	if err := template.RenderStrings(data, &out, func(s string) (string, error) {
+		return renderString(s, ctx)
+	}); err != nil {
+		return data, err
+	}
+	return out, nil
+}
+
+func renderString(s string, ctx map[string]interface{}) (string, error) {
+	if !strings.Contains(s, varsLeftDelim) {
+		return s, nil
+	}
+	tpl, err := template.New("vars").Delims(varsLeftDelim, varsRightDelim).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse variables of %q: %v", s, err)
+	}
+	out := &bytes.Buffer{}
+	if err = tpl.Execute(out, ctx); err != nil {
+		if m := missingKeyRegex.FindStringSubmatch(err.Error()); m != nil {
+			return "", fmt.Errorf("variable %s used in %q is not set, register it or write it to $%s in an earlier step (set variables: %s)",
+				m[1], s, outputEnv, setVariables(ctx))
+		}
+		return "", fmt.Errorf("failed to render variables of %q: %v", s, err)
+	}
+	return out.String(), nil
+}
+
+// setVariables describes the variables in the context for errors.
+func setVariables(ctx map[string]interface{}) string {
+	names := make([]string, 0)
+	if vars, ok := ctx["vars"].(map[string]interface{}); ok {
+		for k := range vars {
+			names = append(names, ".vars."+k)
+		}
+	}
+	if hostVars, ok := ctx["hostVars"].(map[string]interface{}); ok {
+		for host, hv := range hostVars {
+			if m, ok := hv.(map[string]interface{}); ok {
+				for k := range m {
+					names = append(names, fmt.Sprintf(".hostVars.%s.%s", host, k))
+				}
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+func Test_renderData(t *testing.T) {
+	vars := newVariables()
+	vars.set("BIN", "/tmp/sealos")
+	vars.set("SHA", "abc")
+	vars.setHost("default-master-0", "node", map[string]interface{}{"name": "master-0"})
+	ctx := vars.context()
+	tests := []struct {
+		name    string
+		data    v1.ActionData
+		want    v1.ActionData
+		wantErr bool
+	}{
+		{
+			name: "no variables",
+			data: v1.ActionData{ActionExec: "echo {{ .name }} $HOME"},
+			want: v1.ActionData{ActionExec: "echo {{ .name }} $HOME"},
+		},
+		{
+			name: "copy and env",
+			data: v1.ActionData{
+				ActionCopy:  &v1.SourceAndTarget{Source: "${{ .vars.BIN }}", Target: "/usr/bin/sealos"},
+				ExecOptions: v1.ExecOptions{Env: map[string]string{"SHA": "${{ .vars.SHA }}"}},
+			},
+			want: v1.ActionData{
+				ActionCopy:  &v1.SourceAndTarget{Source: "/tmp/sealos", Target: "/usr/bin/sealos"},
+				ExecOptions: v1.ExecOptions{Env: map[string]string{"SHA": "abc"}},
+			},
+		},
+		{
+			name: "host variable",
+			data: v1.ActionData{ActionExec: `echo ${{ index .hostVars "default-master-0" "node" "name" }}`},
+			want: v1.ActionData{ActionExec: "echo master-0"},
+		},
+		{
+			name:    "unset variable",
+			data:    v1.ActionData{ActionExec: "echo ${{ .vars.TOKEN }}"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderData(tt.data, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderData() = %v, want %v", got.String(), tt.want.String())
+			}
+		})
+	}
+}
+
+func Test_parseOutputs(t *testing.T) {
+	got, err := parseOutputs([]byte("# build\nBIN=/tmp/sealos\n\nSHA=abc=def\r\n"))
+	if err != nil {
+		t.Fatalf("parseOutputs() error = %v", err)
+	}
+	want := map[string]string{"BIN": "/tmp/sealos", "SHA": "abc=def"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseOutputs() = %v, want %v", got, want)
+	}
+	if _, err = parseOutputs([]byte("not an output\n")); err == nil {
+		t.Errorf("parseOutputs() error = nil, want error")
+	}
+}
+
+func Test_renderString_unset(t *testing.T) {
+	vars := newVariables()
+	vars.set("SHA", "abc")
+	_, err := renderString("kubeadm join --token ${{ .vars.TOKEN }}", vars.context())
+	want := `variable .vars.TOKEN used in "kubeadm join --token ${{ .vars.TOKEN }}" is not set, register it or write it to $SEALVM_OUTPUT in an earlier step (set variables: .vars.SHA)`
+	if err == nil || err.Error() != want {
+		t.Errorf("renderString() error = %v, want %s", err, want)
+	}
+}
+
+func Test_register(t *testing.T) {
+	m := &action{vars: newVariables()}
+	names := []string{"default-master-0", "default-master-1"}
+	data := v1.ActionData{ActionExec: "cat info.json", Register: "info", RegisterFormat: RegisterFormatJSON}
+	if err := m.register("default-master-1", names, data, []byte(`{"token":"b"}`)); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	if err := m.register("default-master-0", names, data, []byte(`{"token":"a"}`)); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	if err := m.register("default-master-0", names, data, []byte("token: a")); err == nil {
+		t.Errorf("register() error = nil, want error of invalid json")
+	}
+	got, err := renderString(`${{ .vars.info.token }} ${{ index .hostVars "default-master-1" "info" "token" }}`, m.vars.context())
+	if err != nil || got != "a b" {
+		t.Errorf("renderString() = %q, %v, want %q", got, err, "a b")
+	}
+}
+
+func Test_validateRegister(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    v1.ActionData
+		wantErr bool
+	}{
+		{name: "exec", data: v1.ActionData{ActionExec: "kubeadm token create", Register: "token"}},
+		{name: "local json", data: v1.ActionData{ActionLocal: "cat a.json", Register: "a", RegisterFormat: "json"}},
+		{name: "invalid name", data: v1.ActionData{ActionExec: "date", Register: "a-b"}, wantErr: true},
+		{name: "not exec", data: v1.ActionData{ActionUmount: "/mnt", Register: "a"}, wantErr: true},
+		{name: "invalid format", data: v1.ActionData{ActionExec: "date", Register: "a", RegisterFormat: "yaml"}, wantErr: true},
+		{name: "format without register", data: v1.ActionData{ActionExec: "date", RegisterFormat: "json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRegister(tt.data); (err != nil) != tt.wantErr {
+				t.Errorf("validateRegister() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
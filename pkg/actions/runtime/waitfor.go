@@ -0,0 +1,189 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+const (
+	defaultWaitTimeout  = 5 * time.Minute
+	defaultWaitInterval = 2 * time.Second
+)
+
+// probe is a rendered WaitFor, cmd runs on the host and check validates its output.
+type probe struct {
+	desc     string
+	cmd      string
+	check    func(out string) error
+	timeout  time.Duration
+	interval time.Duration
+	// unlimited is true when cmd has no timeout of its own, every attempt is limited by the remaining time
+	unlimited bool
+}
+
+func newProbe(w *v1.WaitFor) (*probe, error) {
+	p := &probe{
+		timeout:  defaultWaitTimeout,
+		interval: defaultWaitInterval,
+		check:    func(string) error { return nil },
+	}
+	if w.Timeout != nil {
+		p.timeout = w.Timeout.Duration
+	}
+	if w.Interval != nil {
+		p.interval = w.Interval.Duration
+	}
+	if p.timeout <= 0 || p.interval <= 0 {
+		return nil, fmt.Errorf("waitFor timeout and interval must be positive")
+	}
+	var match *regexp.Regexp
+	if w.Match != "" {
+		var err error
+		if match, err = regexp.Compile(w.Match); err != nil {
+			return nil, fmt.Errorf("waitFor match %s is invalid: %v", w.Match, err)
+		}
+	}
+	set := 0
+	if w.Port != "" {
+		set++
+		host, port := "127.0.0.1", w.Port
+		if strings.Contains(w.Port, ":") {
+			var err error
+			if host, port, err = net.SplitHostPort(w.Port); err != nil {
+				return nil, fmt.Errorf("waitFor port %s is invalid: %v", w.Port, err)
+			}
+		}
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return nil, fmt.Errorf("waitFor port %s is invalid", w.Port)
+		}
+		p.desc = fmt.Sprintf("port %s:%s", host, port)
+		p.cmd = fmt.Sprintf("timeout 5 bash -c %s", shellQuote(fmt.Sprintf("</dev/tcp/%s/%s", host, port)))
+	}
+	if w.File != "" {
+		set++
+		p.desc = "file " + w.File
+		p.cmd = "test -e " + shellQuote(w.File)
+	}
+	if w.Command != "" {
+		set++
+		p.desc = "command " + w.Command
+		p.cmd = w.Command
+		p.unlimited = true
+		if match != nil {
+			p.check = func(out string) error {
+				if !match.MatchString(out) {
+					return fmt.Errorf("output does not match %s", w.Match)
+				}
+				return nil
+			}
+		}
+	}
+	if w.HTTP != nil {
+		set++
+		if w.HTTP.URL == "" {
+			return nil, fmt.Errorf("waitFor http url is empty")
+		}
+		status := w.HTTP.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		flags := "-sS"
+		if w.HTTP.Insecure {
+			flags += "k"
+		}
+		p.desc = "http " + w.HTTP.URL
+		p.cmd = fmt.Sprintf("curl %s --max-time 10 -w '\\n%%{http_code}' %s", flags, shellQuote(w.HTTP.URL))
+		p.check = func(out string) error {
+			out = strings.TrimRight(out, "\n")
+			body, code := "", out
+			if i := strings.LastIndex(out, "\n"); i >= 0 {
+				body, code = out[:i], out[i+1:]
+			}
+			if code != strconv.Itoa(status) {
+				return fmt.Errorf("status is %s, want %d", code, status)
+			}
+			if match != nil && !match.MatchString(body) {
+				return fmt.Errorf("body does not match %s", w.Match)
+			}
+			return nil
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("waitFor must set exactly one of port, file, command and http")
+	}
+	if match != nil && w.Command == "" && w.HTTP == nil {
+		return nil, fmt.Errorf("waitFor match can only be used with command or http")
+	}
+	return p, nil
+}
+
+// command returns the command of an attempt, a hanging command is killed when the remaining time is over.
+func (p *probe) command(remaining time.Duration) string {
+	if !p.unlimited {
+		return p.cmd
+	}
+	seconds := int64(math.Ceil(remaining.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return fmt.Sprintf("timeout %d bash -c %s", seconds, shellQuote(p.cmd))
+}
+
+func (m *action) WaitFor(names []string, data v1.ActionData) error {
+	if data.ActionWaitFor == nil {
+		return nil
+	}
+	p, err := newProbe(data.ActionWaitFor)
+	if err != nil {
+		return err
+	}
+	return m.runOnHosts(names, "waitFor", func(name string, _, _ io.Writer) error {
+		return m.waitForOnce(name, p)
+	})
+}
+
+func (m *action) waitForOnce(name string, p *probe) error {
+	logger.Info("%s: waiting for %s", name, p.desc)
+	deadline := time.Now().Add(p.timeout)
+	for {
+		out, err := m.CmdOutput(name, p.command(time.Until(deadline)))
+		if err == nil {
+			err = p.check(string(out))
+		}
+		if err == nil {
+			logger.Info("%s: %s is ready", name, p.desc)
+			return nil
+		}
+		if time.Now().Add(p.interval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s on %s: %v", p.timeout, p.desc, name, err)
+		}
+		logger.Debug("%s: %s is not ready: %v", name, p.desc, err)
+		time.Sleep(p.interval)
+	}
+}
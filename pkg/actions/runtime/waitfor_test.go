@@ -0,0 +1,151 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_newProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		waitFor  v1.WaitFor
+		out      string
+		wantCmd  string
+		wantErr  bool
+		checkErr bool
+	}{
+		{
+			name:    "port",
+			waitFor: v1.WaitFor{Port: "6443"},
+			wantCmd: `timeout 5 bash -c '</dev/tcp/127.0.0.1/6443'`,
+		},
+		{
+			name:    "host port",
+			waitFor: v1.WaitFor{Port: "10.0.0.2:2379"},
+			wantCmd: `timeout 5 bash -c '</dev/tcp/10.0.0.2/2379'`,
+		},
+		{
+			name:    "invalid port",
+			waitFor: v1.WaitFor{Port: "abc"},
+			wantErr: true,
+		},
+		{
+			name:    "file",
+			waitFor: v1.WaitFor{File: "/root/.kube/config"},
+			wantCmd: `test -e '/root/.kube/config'`,
+		},
+		{
+			name:     "command not match",
+			waitFor:  v1.WaitFor{Command: "kubectl get nodes", Match: `\sReady`},
+			wantCmd:  "kubectl get nodes",
+			out:      "node-0 NotReady",
+			checkErr: true,
+		},
+		{
+			name:    "command match",
+			waitFor: v1.WaitFor{Command: "kubectl get nodes", Match: `\sReady`},
+			wantCmd: "kubectl get nodes",
+			out:     "node-0 Ready",
+		},
+		{
+			name:    "http",
+			waitFor: v1.WaitFor{HTTP: &v1.HTTPProbe{URL: "https://127.0.0.1:6443/healthz", Insecure: true}, Match: "^ok$"},
+			wantCmd: `curl -sSk --max-time 10 -w '\n%{http_code}' 'https://127.0.0.1:6443/healthz'`,
+			out:     "ok\n200",
+		},
+		{
+			name:     "http status",
+			waitFor:  v1.WaitFor{HTTP: &v1.HTTPProbe{URL: "http://127.0.0.1", Status: 204}},
+			wantCmd:  `curl -sS --max-time 10 -w '\n%{http_code}' 'http://127.0.0.1'`,
+			out:      "\n200",
+			checkErr: true,
+		},
+		{
+			name:    "several probes",
+			waitFor: v1.WaitFor{Port: "22", File: "/tmp"},
+			wantErr: true,
+		},
+		{
+			name:    "match without output",
+			waitFor: v1.WaitFor{File: "/tmp", Match: "a"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newProbe(&tt.waitFor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newProbe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.cmd != tt.wantCmd {
+				t.Errorf("newProbe() cmd = %v, want %v", p.cmd, tt.wantCmd)
+			}
+			wantAttempt := tt.wantCmd
+			if tt.waitFor.Command != "" {
+				wantAttempt = fmt.Sprintf("timeout 90 bash -c '%s'", tt.wantCmd)
+			}
+			if got := p.command(89500 * time.Millisecond); got != wantAttempt {
+				t.Errorf("command() = %v, want %v", got, wantAttempt)
+			}
+			if err = p.check(tt.out); (err != nil) != tt.checkErr {
+				t.Errorf("check() error = %v, checkErr %v", err, tt.checkErr)
+			}
+		})
+	}
+}
+
+type fakeCmdOutput struct {
+	Interface
+	fails int
+}
+
+func (f *fakeCmdOutput) CmdOutput(name, cmd string) ([]byte, error) {
+	if f.fails > 0 {
+		f.fails--
+		return nil, fmt.Errorf("connection refused")
+	}
+	return []byte("ok"), nil
+}
+
+func TestAction_waitForOnce(t *testing.T) {
+	waitFor := &v1.WaitFor{
+		Port:     "6443",
+		Timeout:  &metav1.Duration{Duration: 50 * time.Millisecond},
+		Interval: &metav1.Duration{Duration: time.Millisecond},
+	}
+	p, err := newProbe(waitFor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &action{Interface: &fakeCmdOutput{fails: 2}}
+	if err = m.waitForOnce("default-master-0", p); err != nil {
+		t.Errorf("waitForOnce() error = %v", err)
+	}
+	m = &action{Interface: &fakeCmdOutput{fails: 1000}}
+	if err = m.waitForOnce("default-master-0", p); err == nil {
+		t.Errorf("waitForOnce() want timeout error")
+	}
+}
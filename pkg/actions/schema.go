@@ -0,0 +1,126 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	yutil "github.com/labring/sealvm/pkg/utils/yaml"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type schema map[string]interface{}
+
+// schemaGenerator generates the json schema of the types by reflection, every struct is a definition.
+type schemaGenerator struct {
+	definitions map[string]schema
+}
+
+// Schema returns the json schema of the action files, the same rules as the strict decoding:
+// unknown fields are not allowed and every step sets exactly one step type.
+func Schema() ([]byte, error) {
+	g := &schemaGenerator{definitions: make(map[string]schema)}
+	root := g.schemaOf(reflect.TypeOf(v1.Action{}))
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "SealVM Action"
+	root["definitions"] = g.definitions
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if yutil.IsOpaque(t) {
+		return schema{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == reflect.TypeOf(v1.Action{}) {
+			return g.structSchema(t)
+		}
+		if t == reflect.TypeOf(metav1.ObjectMeta{}) {
+			return schema{
+				"type": "object",
+				"properties": schema{
+					"name":        schema{"type": "string"},
+					"labels":      schema{"type": "object", "additionalProperties": schema{"type": "string"}},
+					"annotations": schema{"type": "object", "additionalProperties": schema{"type": "string"}},
+				},
+			}
+		}
+		if _, ok := g.definitions[t.Name()]; !ok {
+			// reserve the name first, the types may refer to themselves
+			g.definitions[t.Name()] = nil
+			g.definitions[t.Name()] = g.structSchema(t)
+		}
+		return schema{"$ref": "#/definitions/" + t.Name()}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Slice:
+		return schema{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	}
+	return schema{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) schema {
+	properties := make(schema)
+	required := make([]string, 0)
+	for _, f := range yutil.Fields(t) {
+		properties[f.Name] = g.schemaOf(f.Type)
+		if !strings.Contains(f.Tag.Get("json"), "omitempty") {
+			required = append(required, f.Name)
+		}
+	}
+	s := schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	switch t {
+	case reflect.TypeOf(v1.Action{}):
+		properties["apiVersion"] = schema{"const": v1.GroupVersion.String()}
+		properties["kind"] = schema{"const": "Action"}
+		required = []string{"apiVersion", "kind"}
+	case reflect.TypeOf(v1.ActionData{}):
+		steps := make([]schema, 0)
+		for _, step := range stepTypes(allSteps) {
+			steps = append(steps, schema{"required": []string{step}})
+		}
+		s["oneOf"] = steps
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/actions/runtime"
+	"github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// stepTypes returns the json names of the step types set in data.
+func stepTypes(data v1.ActionData) []string {
+	types := make([]string, 0)
+	for _, step := range []struct {
+		name string
+		set  bool
+	}{
+		{"mount", data.ActionMount != nil},
+		{"umount", data.ActionUmount != ""},
+		{"exec", data.ActionExec != ""},
+		{"copy", data.ActionCopy != nil},
+		{"copyContent", data.ActionCopyContent != nil},
+		{"use", data.ActionUse != ""},
+		{"waitFor", data.ActionWaitFor != nil},
+		{"assert", data.ActionAssert != nil},
+		{"local", data.ActionLocal != ""},
+		{"template", data.ActionTemplate != nil},
+	} {
+		if step.set {
+			types = append(types, step.name)
+		}
+	}
+	return types
+}
+
+// allSteps sets every step type, it lists the step types by stepTypes.
+var allSteps = v1.ActionData{
+	ActionMount:       &v1.SourceAndTarget{},
+	ActionUmount:      "-",
+	ActionExec:        "-",
+	ActionCopy:        &v1.SourceAndTarget{},
+	ActionCopyContent: &v1.ContentAndTarget{},
+	ActionUse:         "-",
+	ActionWaitFor:     &v1.WaitFor{},
+	ActionAssert:      &v1.Assert{},
+	ActionLocal:       "-",
+	ActionTemplate:    &v1.Template{},
+}
+
+// envKeyRegex is the name of a shell variable, the env keys are written to the scripts as they are.
+var envKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnv checks that every key of env is a valid name of shell variables.
+func validateEnv(env map[string]string) error {
+	invalid := make([]string, 0)
+	for k := range env {
+		if !envKeyRegex.MatchString(k) {
+			invalid = append(invalid, fmt.Sprintf("%q", k))
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("invalid env name %s, it must match %s", strings.Join(invalid, ", "), envKeyRegex.String())
+	}
+	return nil
+}
+
+// validateData checks that data sets exactly one step type and only the options of it.
+func validateData(data v1.ActionData) error {
+	types := stepTypes(data)
+	switch len(types) {
+	case 0:
+		return fmt.Errorf("no step type is set, set one of %s", strings.Join(stepTypes(allSteps), ", "))
+	case 1:
+	default:
+		return fmt.Errorf("only one step type can be set, got %s; split them into several steps", strings.Join(types, ", "))
+	}
+	step := types[0]
+	if len(data.ActionWith) > 0 && step != "use" {
+		return fmt.Errorf("with can only be used with use")
+	}
+	if step != "exec" && step != "local" && step != "assert" {
+		opts := make([]string, 0)
+		if len(data.Env) > 0 {
+			opts = append(opts, "env")
+		}
+		if data.WorkDir != "" {
+			opts = append(opts, "workdir")
+		}
+		if data.User != "" {
+			opts = append(opts, "user")
+		}
+		if data.Shell != "" {
+			opts = append(opts, "shell")
+		}
+		if data.Timeout != nil {
+			opts = append(opts, "timeout")
+		}
+		if len(opts) > 0 {
+			return fmt.Errorf("%s can only be used with exec, local or assert, not %s", strings.Join(opts, ", "), step)
+		}
+	}
+	if (data.Register != "" || data.RegisterFormat != "") && step != "exec" && step != "local" {
+		return fmt.Errorf("register can only be used with exec or local, not %s", step)
+	}
+	return validateEnv(data.Env)
+}
+
+// validateAction checks every step of the action.
+func validateAction(action *v1.Action) error {
+	errArr := make([]error, 0)
+	if err := validateEnv(action.Spec.Env); err != nil {
+		errArr = append(errArr, fmt.Errorf("spec.env: %v", err))
+	}
+	for i, data := range action.Spec.Data {
+		if err := validateData(data); err != nil {
+			errArr = append(errArr, fmt.Errorf("spec.data[%d]: %v", i, err))
+		}
+	}
+	return errors.NewAggregate(errArr)
+}
+
+// Validate checks the actions of the file strictly without running them: the yaml, the steps, the dependencies
+// and the ons against the cluster of the name, the ons are not checked if the cluster can not be loaded.
+func Validate(name, p string) error {
+	if !file.IsExist(p) {
+		return fmt.Errorf("file %s not exist", p)
+	}
+	data, err := file.ReadAll(p)
+	if err != nil {
+		return err
+	}
+	actions, err := loadActions(data)
+	if err != nil {
+		return err
+	}
+	if _, err = newWorkflow(actions); err != nil {
+		return err
+	}
+	r, err := runtime.NewAction(name)
+	if err != nil {
+		logger.Warn("ons are not validated, failed to load cluster %s: %v", name, err)
+	} else if err = validateOns(r, actions); err != nil {
+		return err
+	}
+	logger.Info("action file %s is valid", p)
+	return nil
+}
+
+// validateOns checks the ons of the actions against the cluster of the runtime.
+func validateOns(r interface {
+	Validate(action *v1.Action) error
+}, actions []v1.Action) error {
+	errArr := make([]error, 0)
+	for i := range actions {
+		if err := r.Validate(&actions[i]); err != nil {
+			errArr = append(errArr, fmt.Errorf("action %s: %v", actions[i].Name, err))
+		}
+	}
+	return errors.NewAggregate(errArr)
+}
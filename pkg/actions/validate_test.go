@@ -0,0 +1,229 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yutil "github.com/labring/sealvm/pkg/utils/yaml"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_validateData(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    v1.ActionData
+		wantErr string
+	}{
+		{
+			name: "exec with options",
+			data: v1.ActionData{ActionExec: "ls", Register: "out", ExecOptions: v1.ExecOptions{User: "root", Timeout: &metav1.Duration{}}},
+		},
+		{
+			name: "use with params",
+			data: v1.ActionData{ActionUse: "golang-install", ActionWith: map[string]string{"version": "1.20"}},
+		},
+		{
+			name:    "no step type",
+			data:    v1.ActionData{ExecOptions: v1.ExecOptions{User: "root"}},
+			wantErr: "no step type is set, set one of mount, umount, exec, copy, copyContent, use, waitFor, assert, local, template",
+		},
+		{
+			name:    "several step types",
+			data:    v1.ActionData{ActionExec: "ls", ActionCopyContent: &v1.ContentAndTarget{}},
+			wantErr: "only one step type can be set, got exec, copyContent",
+		},
+		{
+			name:    "with without use",
+			data:    v1.ActionData{ActionExec: "ls", ActionWith: map[string]string{"a": "b"}},
+			wantErr: "with can only be used with use",
+		},
+		{
+			name:    "exec options of copy",
+			data:    v1.ActionData{ActionCopy: &v1.SourceAndTarget{}, ExecOptions: v1.ExecOptions{WorkDir: "/root", Shell: "sh"}},
+			wantErr: "workdir, shell can only be used with exec, local or assert, not copy",
+		},
+		{
+			name:    "env name with shell code",
+			data:    v1.ActionData{ActionExec: "ls", ExecOptions: v1.ExecOptions{Env: map[string]string{"GOPATH": "/root/go", "A=1; rm -rf /tmp/x;B": "1"}}},
+			wantErr: `invalid env name "A=1; rm -rf /tmp/x;B"`,
+		},
+		{
+			name:    "register of assert",
+			data:    v1.ActionData{ActionAssert: &v1.Assert{Command: "true"}, Register: "out"},
+			wantErr: "register can only be used with exec or local, not assert",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateData(tt.data)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateData() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateData() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_loadActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `---
+apiVersion: virtual-machine.sealos.io/v1
+kind: Action
+metadata:
+  name: a
+spec:
+  ons:
+  - role: node
+  data:
+  - exec: ls
+---
+apiVersion: virtual-machine.sealos.io/v1
+kind: Action
+spec:
+  data:
+  - waitFor: {port: "22", timeout: 1m}
+`,
+			want: 2,
+		},
+		{
+			name: "unknown field",
+			data: `apiVersion: virtual-machine.sealos.io/v1
+kind: Action
+---
+apiVersion: virtual-machine.sealos.io/v1
+kind: Action
+spec:
+  data:
+  - exec: ls
+    workDir: /root
+`,
+			wantErr: `document 2: line 9, column 5: .spec.data[0]: unknown field "workDir"`,
+		},
+		{
+			name:    "wrong kind",
+			data:    "apiVersion: virtual-machine.sealos.io/v1\nkind: VirtualMachine\n",
+			wantErr: `document 1: want kind Action of virtual-machine.sealos.io/v1, got kind "VirtualMachine"`,
+		},
+		{
+			name: "several step types",
+			data: `apiVersion: virtual-machine.sealos.io/v1
+kind: Action
+metadata:
+  name: a
+spec:
+  data:
+  - exec: ls
+    umount: /root
+`,
+			wantErr: "action a: spec.data[0]: only one step type can be set, got umount, exec",
+		},
+		{
+			name:    "invalid yaml",
+			data:    "kind: [Action\n",
+			wantErr: "invalid yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadActions([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadActions() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadActions() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("loadActions() got %d actions, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+// Test_examples checks that the examples in the docs are valid, the library actions are not expanded.
+func Test_examples(t *testing.T) {
+	files, err := filepath.Glob("../../docs/examples/*/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, "../../types/api/v1/action.yaml")
+	for _, f := range files {
+		if strings.Contains(f, "examples/library/") && !strings.HasSuffix(f, "/action.yaml") {
+			// library definitions are not action files
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs, err := yutil.Documents(data)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		for _, doc := range docs {
+			action := v1.Action{}
+			if err = yutil.UnmarshalNodeStrict(doc, &action); err != nil {
+				t.Errorf("%s: %v", f, err)
+				continue
+			}
+			if err = validateAction(&action); err != nil {
+				t.Errorf("%s: %v", f, err)
+			}
+		}
+	}
+}
+
+// TestSchema checks that the committed schema is generated from the types, UPDATE_SCHEMA=1 regenerates it.
+func TestSchema(t *testing.T) {
+	const schemaFile = "../../docs/sealvm/action.schema.json"
+	got, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("UPDATE_SCHEMA") != "" {
+		if err = os.WriteFile(schemaFile, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date, run UPDATE_SCHEMA=1 go test ./pkg/actions -run TestSchema", schemaFile)
+	}
+}
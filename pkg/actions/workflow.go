@@ -0,0 +1,224 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+// node is an action of the workflow with its resolved dependencies.
+type node struct {
+	name   string
+	action *v1.Action
+	deps   []string
+}
+
+// workflow is the DAG of the actions in a file, ordered topologically.
+type workflow struct {
+	nodes    []*node
+	index    map[string]*node
+	selected map[string]bool
+}
+
+// WorkflowResult is the outcome of an action of the workflow.
+type WorkflowResult struct {
+	Name     string
+	Phase    v1.ActionPhase
+	Duration string
+	Message  string
+}
+
+// newWorkflow builds the DAG of the actions. An action without metadata.name is named action-<index>,
+// and when it has no dependsOn it depends on the previous action, so files without names run in order as before.
+func newWorkflow(actions []v1.Action) (*workflow, error) {
+	w := &workflow{
+		index: make(map[string]*node),
+	}
+	nodes := make([]*node, 0, len(actions))
+	for i := range actions {
+		n := &node{
+			name:   actions[i].Name,
+			action: &actions[i],
+			deps:   actions[i].Spec.DependsOn,
+		}
+		if n.name == "" {
+			n.name = fmt.Sprintf("action-%d", i)
+			if len(n.deps) == 0 && i > 0 {
+				n.deps = []string{nodes[i-1].name}
+			}
+			actions[i].Name = n.name
+		}
+		if _, ok := w.index[n.name]; ok {
+			return nil, fmt.Errorf("action name %s is duplicated", n.name)
+		}
+		w.index[n.name] = n
+		nodes = append(nodes, n)
+	}
+	for _, n := range nodes {
+		for _, dep := range n.deps {
+			if _, ok := w.index[dep]; !ok {
+				return nil, fmt.Errorf("action %s depends on unknown action %s", n.name, dep)
+			}
+			if dep == n.name {
+				return nil, fmt.Errorf("action %s depends on itself", n.name)
+			}
+		}
+	}
+	// kahn's algorithm, keeps the order of the file among the actions which are ready
+	inDegree := make(map[string]int, len(nodes))
+	for _, n := range nodes {
+		inDegree[n.name] = len(n.deps)
+	}
+	done := make(map[string]bool, len(nodes))
+	for len(w.nodes) < len(nodes) {
+		progressed := false
+		for _, n := range nodes {
+			if done[n.name] || inDegree[n.name] > 0 {
+				continue
+			}
+			done[n.name] = true
+			progressed = true
+			w.nodes = append(w.nodes, n)
+			for _, m := range nodes {
+				for _, dep := range m.deps {
+					if dep == n.name {
+						inDegree[m.name]--
+					}
+				}
+			}
+		}
+		if !progressed {
+			cycle := make([]string, 0)
+			for _, n := range nodes {
+				if !done[n.name] {
+					cycle = append(cycle, n.name)
+				}
+			}
+			return nil, fmt.Errorf("actions %s have circular dependencies", strings.Join(cycle, ","))
+		}
+	}
+	return w, nil
+}
+
+// Select limits the workflow to the action of only, or to the action of from and every action depending on it.
+// Dependencies outside the selection are treated as satisfied.
+func (w *workflow) Select(only, from string) error {
+	if only != "" && from != "" {
+		return fmt.Errorf("only and from can not be set at the same time")
+	}
+	if only == "" && from == "" {
+		w.selected = nil
+		return nil
+	}
+	start := only
+	if start == "" {
+		start = from
+	}
+	if _, ok := w.index[start]; !ok {
+		return fmt.Errorf("action %s not found", start)
+	}
+	w.selected = map[string]bool{start: true}
+	if from != "" {
+		// nodes are ordered topologically, so one pass reaches all the dependents
+		for _, n := range w.nodes {
+			for _, dep := range n.deps {
+				if w.selected[dep] {
+					w.selected[n.name] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Nodes returns the selected actions in topological order.
+func (w *workflow) Nodes() []*node {
+	nodes := make([]*node, 0, len(w.nodes))
+	for _, n := range w.nodes {
+		if w.selected == nil || w.selected[n.name] {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Run applies the selected actions, every action starts as soon as its dependencies complete,
+// and it is skipped when any of them fails or is skipped.
+func (w *workflow) Run(apply func(action *v1.Action) error) []WorkflowResult {
+	nodes := w.Nodes()
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		results = make(map[string]WorkflowResult, len(nodes))
+		done    = make(map[string]chan struct{}, len(nodes))
+	)
+	for _, n := range nodes {
+		done[n.name] = make(chan struct{})
+	}
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			defer close(done[n.name])
+			failed := make([]string, 0)
+			for _, dep := range n.deps {
+				ch, ok := done[dep]
+				if !ok {
+					continue
+				}
+				<-ch
+				lock.Lock()
+				if results[dep].Phase != v1.ActionPhaseComplete {
+					failed = append(failed, dep)
+				}
+				lock.Unlock()
+			}
+			result := WorkflowResult{Name: n.name}
+			if len(failed) > 0 {
+				n.action.Status.Phase = v1.ActionPhaseSkipped
+				n.action.Status.Message = fmt.Sprintf("dependencies %s did not complete", strings.Join(failed, ","))
+				logger.Warn("skip action %s: %s", n.name, n.action.Status.Message)
+			} else {
+				logger.Info("start action %s", n.name)
+				start := time.Now()
+				if err := apply(n.action); err != nil {
+					logger.Error("apply action %s error: %v", n.name, err)
+					n.action.Status.Phase = v1.ActionPhaseFailed
+					n.action.Status.Message = err.Error()
+				}
+				result.Duration = time.Since(start).Round(time.Millisecond).String()
+			}
+			result.Phase = n.action.Status.Phase
+			result.Message = n.action.Status.Message
+			lock.Lock()
+			results[n.name] = result
+			lock.Unlock()
+		}(n)
+	}
+	wg.Wait()
+	out := make([]WorkflowResult, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, results[n.name])
+	}
+	return out
+}
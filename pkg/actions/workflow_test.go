@@ -0,0 +1,183 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestAction(name string, deps ...string) v1.Action {
+	return v1.Action{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1.ActionSpec{DependsOn: deps},
+	}
+}
+
+func nodeNames(nodes []*node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		names = append(names, n.name)
+	}
+	return names
+}
+
+func Test_newWorkflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []v1.Action
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "unnamed run in order",
+			actions: []v1.Action{newTestAction(""), newTestAction(""), newTestAction("")},
+			want:    []string{"action-0", "action-1", "action-2"},
+		},
+		{
+			name:    "dependencies",
+			actions: []v1.Action{newTestAction("install", "build", "init"), newTestAction("build"), newTestAction("init")},
+			want:    []string{"build", "init", "install"},
+		},
+		{
+			name:    "unknown dependency",
+			actions: []v1.Action{newTestAction("install", "build")},
+			wantErr: true,
+		},
+		{
+			name:    "duplicated name",
+			actions: []v1.Action{newTestAction("build"), newTestAction("build")},
+			wantErr: true,
+		},
+		{
+			name:    "cycle",
+			actions: []v1.Action{newTestAction("a", "b"), newTestAction("b", "a")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := newWorkflow(tt.actions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newWorkflow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := nodeNames(w.Nodes()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newWorkflow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflow_Select(t *testing.T) {
+	actions := []v1.Action{
+		newTestAction("build"),
+		newTestAction("init"),
+		newTestAction("copy", "build"),
+		newTestAction("install", "copy", "init"),
+	}
+	tests := []struct {
+		name    string
+		only    string
+		from    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "all",
+			want: []string{"build", "init", "copy", "install"},
+		},
+		{
+			name: "only",
+			only: "copy",
+			want: []string{"copy"},
+		},
+		{
+			name: "from",
+			from: "build",
+			want: []string{"build", "copy", "install"},
+		},
+		{
+			name:    "not found",
+			from:    "deploy",
+			wantErr: true,
+		},
+		{
+			name:    "only and from",
+			only:    "build",
+			from:    "copy",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := newWorkflow(actions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = w.Select(tt.only, tt.from)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := nodeNames(w.Nodes()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflow_Run(t *testing.T) {
+	w, err := newWorkflow([]v1.Action{
+		newTestAction("build"),
+		newTestAction("init"),
+		newTestAction("copy", "build"),
+		newTestAction("install", "copy", "init"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := w.Run(func(action *v1.Action) error {
+		if action.Name == "copy" {
+			action.Status.Phase = v1.ActionPhaseFailed
+			return fmt.Errorf("copy failed")
+		}
+		action.Status.Phase = v1.ActionPhaseComplete
+		return nil
+	})
+	got := make(map[string]v1.ActionPhase)
+	for _, r := range results {
+		got[r.Name] = r.Phase
+	}
+	want := map[string]v1.ActionPhase{
+		"build":   v1.ActionPhaseComplete,
+		"init":    v1.ActionPhaseComplete,
+		"copy":    v1.ActionPhaseFailed,
+		"install": v1.ActionPhaseSkipped,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Run() = %v, want %v", got, want)
+	}
+}
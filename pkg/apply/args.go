@@ -19,9 +19,14 @@ package apply
 import (
 	"errors"
 	"fmt"
+	"github.com/labring/sealvm/pkg/process"
+	"github.com/labring/sealvm/pkg/ssh"
 	"github.com/labring/sealvm/pkg/template"
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
 	"github.com/labring/sealvm/pkg/utils/logger"
 	v1 "github.com/labring/sealvm/types/api/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -33,6 +38,18 @@ func ValidateTemplate(vm *v1.VirtualMachine) error {
 	if vm.Spec.SSH.PkFile == "" {
 		return fmt.Errorf("private key is required,please set values using 'sealvm values set'")
 	}
+	if err := ssh.ValidateAuthMethods(vm.Spec.SSH.AuthMethods); err != nil {
+		return err
+	}
+	if vm.Spec.SSH.CertFile != "" && !fileutil.IsExist(vm.Spec.SSH.CertFile) {
+		return fmt.Errorf("ssh certificate %s is not exist", vm.Spec.SSH.CertFile)
+	}
+	if vm.Spec.SSH.Port < 0 || vm.Spec.SSH.Port > 65535 {
+		return fmt.Errorf("ssh port %d is out of range", vm.Spec.SSH.Port)
+	}
+	if err := ssh.ValidateProxyJump(vm.Spec.SSH.ProxyJump); err != nil {
+		return err
+	}
 	tpl := template.NewTpl()
 	logger.Debug("current vm roles", vm.GetRoles())
 	for _, r := range vm.GetRoles() {
@@ -86,3 +103,105 @@ func ParseMounts(mounts []string) (map[string]map[string]string, error) {
 	}
 	return mountMap, nil
 }
+
+// node@k=v
+func ParseLabels(labels []string) (map[string]map[string]string, error) {
+	labelMap := make(map[string]map[string]string)
+	for _, label := range labels {
+		labelArr := strings.SplitN(label, "@", 2)
+		if len(labelArr) != 2 {
+			return nil, errors.New("label format is wrong")
+		}
+		labelRole := labelArr[0]
+		kv := strings.SplitN(labelArr[1], "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.New("label format is wrong")
+		}
+		if errs := validation.IsQualifiedName(kv[0]); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %s: %s", kv[0], strings.Join(errs, ";"))
+		}
+		if errs := validation.IsValidLabelValue(kv[1]); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label value %s: %s", kv[1], strings.Join(errs, ";"))
+		}
+		if _, ok := labelMap[labelRole]; !ok {
+			labelMap[labelRole] = map[string]string{kv[0]: kv[1]}
+		} else {
+			labelMap[labelRole][kv[0]] = kv[1]
+		}
+	}
+	return labelMap, nil
+}
+
+// ParseHooks checks the hook files exist and converts them to absolute paths,
+// hooks run later from the saved vm file where relative paths are meaningless.
+func ParseHooks(hooks v1.Hooks) (v1.Hooks, error) {
+	abs := func(files []string) ([]string, error) {
+		if len(files) == 0 {
+			return nil, nil
+		}
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			p, err := filepath.Abs(f)
+			if err != nil {
+				return nil, err
+			}
+			if !fileutil.IsFile(p) {
+				return nil, fmt.Errorf("hook file %s not exist", f)
+			}
+			paths = append(paths, p)
+		}
+		return paths, nil
+	}
+	var (
+		out v1.Hooks
+		err error
+	)
+	if out.PostCreate, err = abs(hooks.PostCreate); err != nil {
+		return out, err
+	}
+	if out.PostScaleUp, err = abs(hooks.PostScaleUp); err != nil {
+		return out, err
+	}
+	if out.PreDelete, err = abs(hooks.PreDelete); err != nil {
+		return out, err
+	}
+	if out.PostStart, err = abs(hooks.PostStart); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// ParseForwards parses the forwards of the spec, locals and remotes are like <host>@<local>:<remote>
+// where host is a host name, a role or role:index, eg: master@6443:6443.
+func ParseForwards(locals, remotes []string) ([]v1.PortForward, error) {
+	var forwards []v1.PortForward
+	parse := func(specs []string, reverse bool) error {
+		for _, spec := range specs {
+			arr := strings.SplitN(spec, "@", 2)
+			if len(arr) != 2 || arr[0] == "" {
+				return fmt.Errorf("forward %s format is wrong, it must be <host>@<local>:<remote>", spec)
+			}
+			var (
+				fs  []v1.PortForward
+				err error
+			)
+			if reverse {
+				fs, err = process.ParsePortForwards(arr[0], nil, []string{arr[1]})
+			} else {
+				fs, err = process.ParsePortForwards(arr[0], []string{arr[1]}, nil)
+			}
+			if err != nil {
+				return err
+			}
+			forwards = append(forwards, fs...)
+		}
+		return nil
+	}
+	if err := parse(locals, false); err != nil {
+		return nil, err
+	}
+	if err := parse(remotes, true); err != nil {
+		return nil, err
+	}
+	return forwards, nil
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package apply
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
 )
 
 func TestParseMounts(t *testing.T) {
@@ -113,3 +117,147 @@ func TestParseNodes(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLabels(t *testing.T) {
+	type args struct {
+		labels []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]map[string]string
+		wantErr bool
+	}{
+		{
+			name: "test",
+			args: args{
+				labels: []string{
+					"master@etcd=true",
+					"node@gpu-sim=true",
+					"node@zone=a",
+				},
+			},
+			wantErr: false,
+			want: map[string]map[string]string{
+				"master": {
+					"etcd": "true",
+				},
+				"node": {
+					"gpu-sim": "true",
+					"zone":    "a",
+				},
+			},
+		},
+		{
+			name: "test-fales",
+			args: args{
+				labels: []string{"etcd=true"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test-fales",
+			args: args{
+				labels: []string{"master@etcd=true false"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLabels(tt.args.labels)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLabels() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLabels() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHooks(t *testing.T) {
+	dir := t.TempDir()
+	hook := filepath.Join(dir, "bootstrap.yaml")
+	if err := os.WriteFile(hook, []byte("kind: Action"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		hooks   v1.Hooks
+		want    v1.Hooks
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			hooks: v1.Hooks{},
+			want:  v1.Hooks{},
+		},
+		{
+			name:  "absolute",
+			hooks: v1.Hooks{PostCreate: []string{hook}, PreDelete: []string{hook}},
+			want:  v1.Hooks{PostCreate: []string{hook}, PreDelete: []string{hook}},
+		},
+		{
+			name:    "not exist",
+			hooks:   v1.Hooks{PostScaleUp: []string{filepath.Join(dir, "none.yaml")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHooks(tt.hooks)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseHooks() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHooks() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForwards(t *testing.T) {
+	tests := []struct {
+		name    string
+		locals  []string
+		remotes []string
+		want    []v1.PortForward
+		wantErr bool
+	}{
+		{
+			name:    "test",
+			locals:  []string{"master@6443:6443", "node:1@0.0.0.0:30080:10.96.0.1:80"},
+			remotes: []string{"default-node-0@8080:3000"},
+			want: []v1.PortForward{
+				{Host: "master", Local: "127.0.0.1:6443", Remote: "127.0.0.1:6443"},
+				{Host: "node:1", Local: "0.0.0.0:30080", Remote: "10.96.0.1:80"},
+				{Host: "default-node-0", Local: "127.0.0.1:3000", Remote: "127.0.0.1:8080", Reverse: true},
+			},
+		},
+		{
+			name:    "test-no-host",
+			locals:  []string{"6443:6443"},
+			wantErr: true,
+		},
+		{
+			name:    "test-invalid-port",
+			remotes: []string{"master@8080:0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseForwards(tt.locals, tt.remotes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseForwards() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseForwards() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"fmt"
+
+	"github.com/labring/sealvm/pkg/actions"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runHook runs a hook file on the hosts, it is replaced by the tests.
+var runHook = actions.RunHook
+
+func (r *VirtualMachine) PostCreateHooks(infra *v1.VirtualMachine) {
+	if len(infra.Spec.Hooks.PostCreate) == 0 {
+		return
+	}
+	if !v1.IsConditionsTrue(infra.Status.Conditions) {
+		logger.Info("Skip to exec PostCreateHooks:", r.Desired.Name)
+		return
+	}
+	logger.Info("Start to exec PostCreateHooks:", r.Desired.Name)
+	r.runHooks(infra, infra, "PostCreateHook", infra.Spec.Hooks.PostCreate, nil)
+}
+
+func (r *VirtualMachine) PostScaleUpHooks(infra *v1.VirtualMachine) {
+	if len(infra.Spec.Hooks.PostScaleUp) == 0 || r.Current == nil {
+		return
+	}
+	if !v1.IsConditionsTrue(infra.Status.Conditions) {
+		logger.Info("Skip to exec PostScaleUpHooks:", r.Desired.Name)
+		return
+	}
+	addHostNames, _ := r.DiffFunc(r.Current, r.Desired)
+	if len(addHostNames) == 0 {
+		return
+	}
+	logger.Info("Start to exec PostScaleUpHooks:", r.Desired.Name)
+	r.runHooks(infra, infra, "PostScaleUpHook", infra.Spec.Hooks.PostScaleUp, addHostNames)
+}
+
+// PostStartHooks runs the postStart hooks on the hosts started by StartVMs.
+func (r *VirtualMachine) PostStartHooks(infra *v1.VirtualMachine) {
+	if len(infra.Spec.Hooks.PostStart) == 0 || len(r.started) == 0 {
+		return
+	}
+	if !v1.IsConditionsTrue(infra.Status.Conditions) {
+		logger.Info("Skip to exec PostStartHooks:", r.Desired.Name)
+		return
+	}
+	logger.Info("Start to exec PostStartHooks:", r.Desired.Name)
+	r.runHooks(infra, infra, "PostStartHook", infra.Spec.Hooks.PostStart, r.started)
+}
+
+// PreDeleteHooks runs the preDelete hooks of the saved vm before all hosts are deleted,
+// a failed hook is recorded but does not stop the deletion.
+func (r *VirtualMachine) PreDeleteHooks(infra *v1.VirtualMachine) {
+	if r.Current == nil || len(r.Current.Spec.Hooks.PreDelete) == 0 {
+		return
+	}
+	logger.Info("Start to exec PreDeleteHooks:", r.Desired.Name)
+	r.runHooks(infra, r.Current, "PreDeleteHook", r.Current.Spec.Hooks.PreDelete, nil)
+}
+
+// runHooks applies the hook files on the hosts of vm and records the outcome as the condition of infra.
+func (r *VirtualMachine) runHooks(infra, vm *v1.VirtualMachine, hook string, files []string, hosts []string) {
+	var condition = &v1.Condition{
+		Type:              hook,
+		Status:            v12.ConditionTrue,
+		Reason:            "Hook Succeeded",
+		Message:           fmt.Sprintf("hook %v has been applied", files),
+		LastHeartbeatTime: metav1.Now(),
+	}
+	defer r.saveCondition(infra, condition)
+	for _, f := range files {
+		if err := runHook(vm, f, hosts); err != nil {
+			logger.Error("failed to run hook %s: %v", f, err)
+			v1.SetConditionError(condition, "HookError", fmt.Errorf("failed to run hook %s: %v", f, err))
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/labring/sealvm/pkg/utils/strings"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+// fakeProvider reports the hosts in states as stopped until they are started.
+type fakeProvider struct {
+	Interface
+	lock   sync.Mutex
+	states map[string]string
+}
+
+func (f *fakeProvider) Inspect(name string, role v1.Host, index int) (*v1.VirtualMachineHostStatus, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	id := strings.GetID(name, role.Role, index)
+	state, ok := f.states[id]
+	if !ok {
+		return nil, errors.New("not found instance")
+	}
+	return &v1.VirtualMachineHostStatus{ID: id, Role: role.Role, State: state}, nil
+}
+
+func (f *fakeProvider) InspectByList(name string, role v1.Host, index int) (*v1.VirtualMachineHostStatus, error) {
+	return f.Inspect(name, role, index)
+}
+
+func (f *fakeProvider) StartVM(_ *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.states[host.ID] = "Running"
+	return nil
+}
+
+func TestPostStartHooks(t *testing.T) {
+	var got []string
+	defer func(fn func(*v1.VirtualMachine, string, []string) error) { runHook = fn }(runHook)
+	runHook = func(_ *v1.VirtualMachine, p string, hosts []string) error {
+		got = append(got, p)
+		got = append(got, hosts...)
+		return nil
+	}
+
+	desired := &v1.VirtualMachine{}
+	desired.Name = "default"
+	desired.Spec.Hosts = []v1.Host{{Role: "node", Count: 3}}
+	desired.Spec.Hooks.PostStart = []string{"/tmp/start.yaml"}
+	current := desired.DeepCopy()
+	current.Spec.Hosts[0].Count = 2
+	provider := &fakeProvider{states: map[string]string{
+		"default-node-0": "Running",
+		"default-node-1": "Stopped",
+		"default-node-2": "Running",
+	}}
+	r := &VirtualMachine{
+		Desired: desired,
+		Current: current,
+		DiffFunc: func(old, new *v1.VirtualMachine) (add, delete []string) {
+			return []string{"default-node-2"}, nil
+		},
+		Interface: provider,
+	}
+	r.StartVMs(desired)
+	if provider.states["default-node-1"] != "Running" {
+		t.Fatalf("StartVMs() did not start the stopped host: %v", provider.states)
+	}
+	r.PostStartHooks(desired)
+	sort.Strings(got[1:])
+	if want := []string{"/tmp/start.yaml", "default-node-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PostStartHooks() ran %v, want %v", got, want)
+	}
+
+	// nothing is started by the next reconcile, the hook does not run again
+	got = nil
+	r = &VirtualMachine{Desired: desired, Current: desired, DiffFunc: r.DiffFunc, Interface: provider}
+	r.StartVMs(desired)
+	r.PostStartHooks(desired)
+	if len(got) != 0 {
+		t.Errorf("PostStartHooks() ran %v without started hosts", got)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/labring/sealvm/pkg/configs"
+	"github.com/labring/sealvm/pkg/ssh"
 	"github.com/labring/sealvm/pkg/template"
 	fileutil "github.com/labring/sealvm/pkg/utils/file"
 	"github.com/labring/sealvm/pkg/utils/logger"
@@ -52,6 +53,7 @@ func (r *VirtualMachine) Init() {
 		r.CreateVMs,
 		r.SyncVMs,
 		r.PingVms,
+		r.PostCreateHooks,
 		r.FinalStatus,
 	}
 
@@ -176,6 +178,12 @@ func (r *VirtualMachine) SyncVMs(infra *v1.VirtualMachine) {
 				v1.SetConditionError(configCondition, "VMStatus", fmt.Errorf("vm %s status is not running", strings.GetID(infra.Name, host.Role, i)))
 				continue
 			}
+			if old := infra.GetHostStatusByName(info.ID); old != nil {
+				info.Labels = old.Labels
+			} else {
+				// the host is new, a key of its ips is of a deleted host
+				forgetHostKeys(infra, info)
+			}
 			status = append(status, *info)
 		}
 	}
@@ -212,6 +220,13 @@ func (r *VirtualMachine) PingVms(infra *v1.VirtualMachine) {
 	}
 }
 
+// forgetHostKeys removes the host keys of the ips of the host from the known hosts file of the cluster.
+func forgetHostKeys(infra *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) {
+	if err := ssh.RemoveKnownHosts(configs.KnownHostsFilePath(infra.Name), host.IPs...); err != nil {
+		logger.Warn("failed to remove the host keys of %s: %v", host.ID, err)
+	}
+}
+
 func (r *VirtualMachine) FinalStatus(infra *v1.VirtualMachine) {
 	condition := &v1.Condition{
 		Type:              "Ready",
@@ -47,6 +47,11 @@ func (r *multipass) DeleteVM(infra *v1.VirtualMachine, host *v1.VirtualMachineHo
 	return nil
 }
 
+func (r *multipass) StartVM(infra *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) error {
+	cmd := fmt.Sprintf("multipass start %s", host.ID)
+	return exec.Cmd("bash", "-c", cmd)
+}
+
 func (r *multipass) Get(name, role string, index int) (string, error) {
 	cmd := fmt.Sprintf("multipass info %s --format=json", strings.GetID(name, role, index))
 	out, _ := exec.RunBashCmd(cmd)
@@ -193,7 +198,8 @@ func (r *multipass) Inspect(name string, role v1.Host, index int) (*v1.VirtualMa
 	return hostStatus, nil
 }
 func (r *multipass) PingVmsForHosts(infra *v1.VirtualMachine, hosts []v1.VirtualMachineHostStatus) error {
-	client := ssh.NewSSHClient(&infra.Spec.SSH, true)
+	client := ssh.NewSSHClient(infra, true)
+	defer client.Close()
 	var ips []string
 	for _, host := range hosts {
 		ips = append(ips, host.IPs[0])
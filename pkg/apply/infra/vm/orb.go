@@ -63,6 +63,11 @@ func (r *orb) DeleteVM(infra *v1.VirtualMachine, host *v1.VirtualMachineHostStat
 	return nil
 }
 
+func (r *orb) StartVM(infra *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) error {
+	cmd := fmt.Sprintf("orbctl start %s", host.ID)
+	return exec.Cmd("bash", "-c", cmd)
+}
+
 func (r *orb) Get(name, role string, index int) (string, error) {
 	cmd := fmt.Sprintf("orb info %s --format json", strings.GetID(name, role, index))
 	out, _ := exec.RunBashCmd(cmd)
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/labring/sealvm/pkg/apply/runtime"
@@ -38,13 +39,17 @@ func (r *VirtualMachine) Reconcile(diff runtime.Diff) {
 		r.InitStatus,
 		r.ApplyConfig,
 		r.ApplyVMs,
+		r.StartVMs,
 		r.SyncVMs,
 		r.PingVms,
+		r.PostScaleUpHooks,
+		r.PostStartHooks,
 		r.FinalStatus,
 	}
 	if !r.Desired.DeletionTimestamp.IsZero() {
 		pipelines = []func(infra *v1.VirtualMachine){
 			r.InitStatus,
+			r.PreDeleteHooks,
 			r.DeleteVMs,
 		}
 	}
@@ -67,6 +72,9 @@ func (r *VirtualMachine) ApplyVMs(infra *v1.VirtualMachine) {
 	}
 	defer r.saveCondition(infra, configCondition)
 	addHostNames, deleteHostNames := r.DiffFunc(r.Current, r.Desired)
+	if len(deleteHostNames) > 0 && len(infra.Spec.Hooks.PreDelete) > 0 {
+		r.runHooks(infra, r.Current, "PreDeleteHook", infra.Spec.Hooks.PreDelete, deleteHostNames)
+	}
 
 	eg, _ := errgroup.WithContext(context.Background())
 
@@ -95,7 +103,11 @@ func (r *VirtualMachine) ApplyVMs(infra *v1.VirtualMachine) {
 			if err == nil {
 				hostStatus := r.Current.GetHostStatusByRoleIndex(role, indexInt)
 				if hostStatus != nil {
-					return r.DeleteVM(r.Current, hostStatus)
+					if err = r.DeleteVM(r.Current, hostStatus); err != nil {
+						return err
+					}
+					forgetHostKeys(r.Current, hostStatus)
+					return nil
 				}
 				return fmt.Errorf("not found host status from role: %s, index: %d", role, indexInt)
 			}
@@ -109,6 +121,54 @@ func (r *VirtualMachine) ApplyVMs(infra *v1.VirtualMachine) {
 	}
 }
 
+// StartVMs starts the hosts of the spec which exist but are not running, the started hosts
+// run the postStart hooks after they are synced.
+func (r *VirtualMachine) StartVMs(infra *v1.VirtualMachine) {
+	logger.Info("Start to exec StartVMs:", r.Desired.Name)
+	var configCondition = &v1.Condition{
+		Type:              "StartVMs",
+		Status:            v12.ConditionTrue,
+		Reason:            "VM start",
+		Message:           "stopped vm instances have been started",
+		LastHeartbeatTime: metav1.Now(),
+	}
+	defer r.saveCondition(infra, configCondition)
+	addHostNames, _ := r.DiffFunc(r.Current, r.Desired)
+	var lock sync.Mutex
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, host := range infra.Spec.Hosts {
+		for i := 0; i < host.Count; i++ {
+			h, index := host, i
+			if strings.In(strings.GetID(infra.Name, h.Role, index), addHostNames) {
+				continue
+			}
+			eg.Go(func() error {
+				info, err := r.Inspect(infra.Name, h, index)
+				if err != nil {
+					if info, err = r.InspectByList(infra.Name, h, index); err != nil {
+						// the missing host is reported by SyncVMs
+						return nil
+					}
+				}
+				if info.IsRunning() {
+					return nil
+				}
+				if err = r.StartVM(infra, info); err != nil {
+					return fmt.Errorf("failed to start vm %s: %v", info.ID, err)
+				}
+				lock.Lock()
+				r.started = append(r.started, info.ID)
+				lock.Unlock()
+				return nil
+			})
+		}
+	}
+	if err := eg.Wait(); err != nil {
+		v1.SetConditionError(configCondition, "StartVMsError", err)
+		return
+	}
+}
+
 func (r *VirtualMachine) DeleteVMs(infra *v1.VirtualMachine) {
 	logger.Info("Start to exec DeleteVMs:", r.Desired.Name)
 	var configCondition = &v1.Condition{
@@ -125,7 +185,11 @@ func (r *VirtualMachine) DeleteVMs(infra *v1.VirtualMachine) {
 	for _, host := range infra.Status.Hosts {
 		dHost := host
 		eg.Go(func() error {
-			return r.DeleteVM(infra, &dHost)
+			if err := r.DeleteVM(infra, &dHost); err != nil {
+				return err
+			}
+			forgetHostKeys(infra, &dHost)
+			return nil
 		})
 	}
 	if err := eg.Wait(); err != nil {
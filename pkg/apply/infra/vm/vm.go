@@ -28,11 +28,14 @@ type VirtualMachine struct {
 	Config   configs.Interface
 	DiffFunc runtime.Diff
 	Interface
+	// started are the ids of the hosts started by StartVMs
+	started []string
 }
 
 type Interface interface {
 	CreateVM(infra *v1.VirtualMachine, host *v1.Host, index int) error
 	DeleteVM(infra *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) error
+	StartVM(infra *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) error
 	Get(name, role string, index int) (string, error)
 	InspectByList(name string, role v1.Host, index int) (*v1.VirtualMachineHostStatus, error)
 	GetById(name string) (string, error)
@@ -57,6 +57,11 @@ func VirtualMachineFilePath(clusterName string) string {
 	return path.Join(GetDataDir(clusterName), "VirtualMachineFile")
 }
 
+// KnownHostsFilePath is the known hosts file of the ssh host keys of the vms in the cluster.
+func KnownHostsFilePath(clusterName string) string {
+	return path.Join(GetDataDir(clusterName), "known_hosts")
+}
+
 func (c *VirtualMachineFile) Process() (err error) {
 	if !fileutil.IsExist(VirtualMachineFilePath(c.name)) {
 		return ErrVirtualMachineFileNotExists
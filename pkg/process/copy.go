@@ -0,0 +1,115 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/ssh"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+var roleIndexPrefix = regexp.MustCompile(`^[0-9]+:`)
+
+// parseRemotePath parses <target>:<path> where the target is a host name, a role or role:index,
+// ok is false when p is a local path. A single letter before the colon is a windows drive, not a target.
+func parseRemotePath(p string) (target, remotePath string, ok bool) {
+	target, remotePath, found := strings.Cut(p, ":")
+	if !found || len(target) <= 1 || strings.ContainsAny(target, `/\`) {
+		return "", "", false
+	}
+	if roleIndexPrefix.MatchString(remotePath) {
+		index, rest, _ := strings.Cut(remotePath, ":")
+		target, remotePath = target+":"+index, rest
+	}
+	return target, remotePath, true
+}
+
+// Copy copies files between the local host and the hosts of the cluster, one of src and dst is <target>:<path>.
+// Uploads are incremental like the copy steps of the actions, and downloads from several hosts are written to
+// a directory of every host in dst. Directories are copied recursively with the modes of the files.
+func Copy(vm *v1.VirtualMachine, src, dst string) error {
+	srcTarget, srcPath, srcRemote := parseRemotePath(src)
+	dstTarget, dstPath, dstRemote := parseRemotePath(dst)
+	if srcRemote == dstRemote {
+		return fmt.Errorf("one of %s and %s must be a remote path like master:/root/foo", src, dst)
+	}
+	target, remotePath := dstTarget, dstPath
+	if srcRemote {
+		target, remotePath = srcTarget, srcPath
+	}
+	if remotePath == "" {
+		return fmt.Errorf("remote path of %s is empty", target)
+	}
+	hosts, err := ResolveTargets(vm, target)
+	if err != nil {
+		return err
+	}
+	if dstRemote {
+		if _, err = os.Stat(src); err != nil {
+			return err
+		}
+	}
+	clients, err := newHostClients(vm, hosts)
+	if err != nil {
+		return err
+	}
+	defer closeClients(clients)
+	return forEachHost(hosts, func(host *v1.VirtualMachineHostStatus) error {
+		client := clients[host.ID]
+		if dstRemote {
+			return upload(client, host, src, dstPath)
+		}
+		local := dst
+		if len(hosts) > 1 {
+			local = filepath.Join(dst, host.ID) + string(filepath.Separator)
+		}
+		return download(client, host, srcPath, local)
+	})
+}
+
+// upload copies src to the remote path of the host, src is copied into the remote path when it is a directory.
+func upload(client hostClient, host *v1.VirtualMachineHostStatus, src, remotePath string) error {
+	if strings.HasSuffix(remotePath, "/") || client.IsRemoteDir(client.addr, remotePath) {
+		remotePath = path.Join(remotePath, filepath.Base(src))
+	}
+	result, err := client.Sync(client.addr, src, remotePath, ssh.SyncOptions{Progress: "copying files to " + host.ID})
+	if err != nil {
+		return err
+	}
+	logger.Info("%s: copy %s to %s: %s", host.ID, src, remotePath, result)
+	return nil
+}
+
+// download copies the remote path of the host to local, the remote path is copied into local when it is a directory.
+func download(client hostClient, host *v1.VirtualMachineHostStatus, remotePath, local string) error {
+	if info, err := os.Stat(local); (err == nil && info.IsDir()) || strings.HasSuffix(local, string(filepath.Separator)) || strings.HasSuffix(local, "/") {
+		local = filepath.Join(local, path.Base(remotePath))
+	}
+	count, err := client.Download(client.addr, remotePath, local)
+	if err != nil {
+		return err
+	}
+	logger.Info("%s: copy %s to %s: %d files", host.ID, remotePath, local, count)
+	return nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import "testing"
+
+func Test_parseRemotePath(t *testing.T) {
+	tests := []struct {
+		p          string
+		wantTarget string
+		wantPath   string
+		wantOK     bool
+	}{
+		{p: "master:/etc/foo", wantTarget: "master", wantPath: "/etc/foo", wantOK: true},
+		{p: "node:1:/etc/foo", wantTarget: "node:1", wantPath: "/etc/foo", wantOK: true},
+		{p: "default-node-0:foo", wantTarget: "default-node-0", wantPath: "foo", wantOK: true},
+		{p: "node:", wantTarget: "node", wantPath: "", wantOK: true},
+		{p: "/etc/foo", wantOK: false},
+		{p: "./a:b", wantOK: false},
+		{p: `C:\Users\foo`, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.p, func(t *testing.T) {
+			target, path, ok := parseRemotePath(tt.p)
+			if target != tt.wantTarget || path != tt.wantPath || ok != tt.wantOK {
+				t.Errorf("parseRemotePath() = %q, %q, %v, want %q, %q, %v", target, path, ok, tt.wantTarget, tt.wantPath, tt.wantOK)
+			}
+		})
+	}
+}
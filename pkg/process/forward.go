@@ -0,0 +1,84 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/labring/sealvm/pkg/ssh"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+// ParsePortForwards returns the forwards of the host, locals are like the -L of ssh and remotes are like the -R.
+func ParsePortForwards(host string, locals, remotes []string) ([]v1.PortForward, error) {
+	forwards := make([]v1.PortForward, 0, len(locals)+len(remotes))
+	for _, spec := range locals {
+		listen, target, err := ssh.ParseForward(spec)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, v1.PortForward{Host: host, Local: listen, Remote: target})
+	}
+	for _, spec := range remotes {
+		listen, target, err := ssh.ParseForward(spec)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, v1.PortForward{Host: host, Local: target, Remote: listen, Reverse: true})
+	}
+	return forwards, nil
+}
+
+// PortForward runs the forwards of the vm until ctx is done. All the forwards stop when any of them
+// fails, like a local port in use.
+func PortForward(ctx context.Context, vm *v1.VirtualMachine, forwards []v1.PortForward) error {
+	if len(forwards) == 0 {
+		return fmt.Errorf("no port forward of cluster %s", vm.Name)
+	}
+	hosts := make([]*v1.VirtualMachineHostStatus, len(forwards))
+	for i, f := range forwards {
+		host, err := ResolveHost(vm, f.Host)
+		if err != nil {
+			return err
+		}
+		hosts[i] = host
+	}
+	clients, err := newHostClients(vm, hosts)
+	if err != nil {
+		return err
+	}
+	defer closeClients(clients)
+	eg, ctx := errgroup.WithContext(ctx)
+	for i, f := range forwards {
+		client, f := clients[hosts[i].ID], f
+		eg.Go(func() error {
+			var err error
+			if f.Reverse {
+				err = client.RemoteForward(ctx, client.addr, f.Remote, f.Local)
+			} else {
+				err = client.LocalForward(ctx, client.addr, f.Local, f.Remote)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %v", f.Host, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
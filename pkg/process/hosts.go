@@ -0,0 +1,157 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/ssh"
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+// orbHostSuffix is the suffix of the orb host name in the ips of the orb hosts, eg: default-node-0@orb.
+const orbHostSuffix = "@orb"
+
+// ResolveHost returns the host of the target, the target is a host name like default-node-0,
+// a role like node for its first host or a role and index like node:1.
+func ResolveHost(vm *v1.VirtualMachine, target string) (*v1.VirtualMachineHostStatus, error) {
+	if host := vm.GetHostStatusByName(target); host != nil {
+		return host, nil
+	}
+	role, index := target, 0
+	if r, i, ok := strings.Cut(target, ":"); ok {
+		n, err := strconv.Atoi(i)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid host %s, the index of role %s must be a non-negative integer", target, r)
+		}
+		role, index = r, n
+	}
+	if vm.GetHostByRole(role) == nil {
+		return nil, fmt.Errorf("host %s not found in cluster %s, it is neither a host name nor a role of %s", target, vm.Name, strings.Join(vm.GetRoles(), ","))
+	}
+	host := vm.GetHostStatusByRoleIndex(role, index)
+	if host == nil {
+		return nil, fmt.Errorf("host %s not found in cluster %s, role %s has no host of index %d", target, vm.Name, role, index)
+	}
+	return host, nil
+}
+
+// ResolveTargets returns the hosts of the target, the target is a host name, a role for all its hosts
+// or a role and index like node:1 for a single host.
+func ResolveTargets(vm *v1.VirtualMachine, target string) ([]*v1.VirtualMachineHostStatus, error) {
+	if strings.Contains(target, ":") || vm.GetHostStatusByName(target) != nil {
+		host, err := ResolveHost(vm, target)
+		if err != nil {
+			return nil, err
+		}
+		return []*v1.VirtualMachineHostStatus{host}, nil
+	}
+	if vm.GetHostByRole(target) == nil {
+		return nil, fmt.Errorf("host %s not found in cluster %s, it is neither a host name nor a role of %s", target, vm.Name, strings.Join(vm.GetRoles(), ","))
+	}
+	hosts := make([]*v1.VirtualMachineHostStatus, 0)
+	for i := range vm.Status.Hosts {
+		if vm.Status.Hosts[i].Role == target {
+			hosts = append(hosts, &vm.Status.Hosts[i])
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("role %s has no host in cluster %s", target, vm.Name)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Index < hosts[j].Index
+	})
+	return hosts, nil
+}
+
+// SSHAddress returns the address to ssh the host, it is the first ip of the host.
+// The orb host name like default-node-0@orb is skipped, it is only known by the ssh of orb.
+func SSHAddress(host *v1.VirtualMachineHostStatus) (string, error) {
+	for _, ip := range host.IPs {
+		if !strings.HasSuffix(ip, orbHostSuffix) {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("host %s has no ip, is it running? its state is %s", host.ID, host.State)
+}
+
+// orbSSH returns the ssh of the orb host by the ssh proxy of orb, it logins the host by the user like
+// root@default-node-0 and the key of orb. ok is false when the host is not an orb host.
+func orbSSH(vm *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) (spec v1.SSH, ok bool) {
+	if !isOrbHost(host) {
+		return v1.SSH{}, false
+	}
+	user := vm.Spec.SSH.User
+	if user == "" {
+		user = "root"
+	}
+	return v1.SSH{
+		PkFile:       filepath.Join(fileutil.GetHomeDir(), orbIdentityFile),
+		ForwardAgent: vm.Spec.SSH.ForwardAgent,
+		User:         fmt.Sprintf("%s@%s", user, host.ID),
+		Port:         orbSSHPort,
+	}, true
+}
+
+// NewHostSSHClient returns the ssh client of the host and the address to connect it by the client.
+// The orb hosts are connected by the ssh proxy of orb, the others by the ssh of the vm and their first ip.
+func NewHostSSHClient(vm *v1.VirtualMachine, host *v1.VirtualMachineHostStatus) (ssh.Interface, string, error) {
+	if spec, ok := orbSSH(vm, host); ok {
+		orbVM := vm.DeepCopy()
+		orbVM.Spec.SSH = spec
+		return ssh.NewSSHClient(orbVM, false), orbSSHAddress, nil
+	}
+	addr, err := SSHAddress(host)
+	if err != nil {
+		return nil, "", err
+	}
+	return ssh.NewSSHClient(vm, false), addr, nil
+}
+
+// hostClient is the ssh client of a host and the address to connect it.
+type hostClient struct {
+	ssh.Interface
+	addr string
+}
+
+// newHostClients returns the ssh clients of the hosts by their ids, closeClients closes them.
+func newHostClients(vm *v1.VirtualMachine, hosts []*v1.VirtualMachineHostStatus) (map[string]hostClient, error) {
+	clients := make(map[string]hostClient, len(hosts))
+	for _, host := range hosts {
+		if _, ok := clients[host.ID]; ok {
+			continue
+		}
+		client, addr, err := NewHostSSHClient(vm, host)
+		if err != nil {
+			closeClients(clients)
+			return nil, err
+		}
+		clients[host.ID] = hostClient{Interface: client, addr: addr}
+	}
+	return clients, nil
+}
+
+func closeClients(clients map[string]hostClient) {
+	for _, client := range clients {
+		_ = client.Close()
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealvm/pkg/ssh"
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestVM() *v1.VirtualMachine {
+	return &v1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1.VirtualMachineSpec{
+			Hosts: []v1.Host{
+				{Role: "master", Count: 1},
+				{Role: "node", Count: 2},
+			},
+		},
+		Status: v1.VirtualMachineStatus{
+			Hosts: []v1.VirtualMachineHostStatus{
+				{ID: "default-master-0", Role: "master", Index: 0, IPs: []string{"default-master-0@orb", "192.168.64.2"}},
+				{ID: "default-node-0", Role: "node", Index: 0, IPs: []string{"192.168.64.3"}},
+				{ID: "default-node-1", Role: "node", Index: 1, State: "Stopped"},
+			},
+		},
+	}
+}
+
+func TestResolveHost(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    string
+		wantErr string
+	}{
+		{target: "default-node-1", want: "default-node-1"},
+		{target: "master", want: "default-master-0"},
+		{target: "node:1", want: "default-node-1"},
+		{target: "worker", wantErr: "it is neither a host name nor a role of master,node"},
+		{target: "node:2", wantErr: "role node has no host of index 2"},
+		{target: "node:x", wantErr: "the index of role node must be a non-negative integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := ResolveHost(newTestVM(), tt.target)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveHost() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveHost() error = %v", err)
+			}
+			if got.ID != tt.want {
+				t.Errorf("ResolveHost() = %s, want %s", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSHAddress(t *testing.T) {
+	vm := newTestVM()
+	tests := []struct {
+		host    *v1.VirtualMachineHostStatus
+		want    string
+		wantErr bool
+	}{
+		{host: &vm.Status.Hosts[0], want: "192.168.64.2"},
+		{host: &vm.Status.Hosts[1], want: "192.168.64.3"},
+		{host: &vm.Status.Hosts[2], wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host.ID, func(t *testing.T) {
+			got, err := SSHAddress(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SSHAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SSHAddress() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTargets(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    []string
+		wantErr bool
+	}{
+		{target: "node", want: []string{"default-node-0", "default-node-1"}},
+		{target: "node:1", want: []string{"default-node-1"}},
+		{target: "default-master-0", want: []string{"default-master-0"}},
+		{target: "worker", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := ResolveTargets(newTestVM(), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			names := make([]string, 0)
+			for _, h := range got {
+				names = append(names, h.ID)
+			}
+			if !tt.wantErr && strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ResolveTargets() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHostSSHClient(t *testing.T) {
+	vm := newTestVM()
+	vm.Spec.SSH.PkFile = "/root/.ssh/id_rsa"
+	tests := []struct {
+		host     *v1.VirtualMachineHostStatus
+		wantAddr string
+		wantUser string
+		wantPort int
+		wantKey  string
+	}{
+		{host: &vm.Status.Hosts[0], wantAddr: orbSSHAddress, wantUser: "root@default-master-0", wantPort: orbSSHPort,
+			wantKey: filepath.Join(fileutil.GetHomeDir(), orbIdentityFile)},
+		{host: &vm.Status.Hosts[1], wantAddr: "192.168.64.3", wantUser: "root", wantKey: "/root/.ssh/id_rsa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host.ID, func(t *testing.T) {
+			client, addr, err := NewHostSSHClient(vm, tt.host)
+			if err != nil {
+				t.Fatalf("NewHostSSHClient() error = %v", err)
+			}
+			defer client.Close()
+			if addr != tt.wantAddr {
+				t.Errorf("NewHostSSHClient() addr = %s, want %s", addr, tt.wantAddr)
+			}
+			s := client.(*ssh.SSH)
+			if s.User != tt.wantUser || s.Port != tt.wantPort || s.PkFile != tt.wantKey {
+				t.Errorf("NewHostSSHClient() user, port, key = %s, %d, %s, want %s, %d, %s",
+					s.User, s.Port, s.PkFile, tt.wantUser, tt.wantPort, tt.wantKey)
+			}
+		})
+	}
+	if _, _, err := NewHostSSHClient(vm, &vm.Status.Hosts[2]); err == nil {
+		t.Errorf("NewHostSSHClient() of a host without ip succeeded")
+	}
+}
@@ -24,6 +24,7 @@ type Interface interface {
 	List() error
 	Inspect(name string)
 	VMInfo() *v1.VirtualMachine
+	Label(name string, labels []string) error
 }
 
 type defaultProcess struct {
@@ -41,3 +42,7 @@ func (mp *defaultProcess) Inspect(name string) {
 func (mp *defaultProcess) VMInfo() *v1.VirtualMachine {
 	return mp.vm
 }
+
+func (mp *defaultProcess) Label(name string, labels []string) error {
+	return labelHost(mp.vm, name, labels)
+}
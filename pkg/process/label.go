@@ -0,0 +1,88 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/configs"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/labring/sealvm/pkg/utils/yaml"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ParseLabels parses k=v to set a label and k- to remove a label.
+func ParseLabels(labels []string) (set map[string]string, remove []string, err error) {
+	set = make(map[string]string)
+	for _, l := range labels {
+		if strings.HasSuffix(l, "-") && !strings.Contains(l, "=") {
+			key := strings.TrimSuffix(l, "-")
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return nil, nil, fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, ";"))
+			}
+			remove = append(remove, key)
+			continue
+		}
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 {
+			return nil, nil, fmt.Errorf("invalid label %q, must be k=v or k-", l)
+		}
+		if errs := validation.IsQualifiedName(kv[0]); len(errs) > 0 {
+			return nil, nil, fmt.Errorf("invalid label key %q: %s", kv[0], strings.Join(errs, ";"))
+		}
+		if errs := validation.IsValidLabelValue(kv[1]); len(errs) > 0 {
+			return nil, nil, fmt.Errorf("invalid label value %q: %s", kv[1], strings.Join(errs, ";"))
+		}
+		set[kv[0]] = kv[1]
+	}
+	return set, remove, nil
+}
+
+func labelHost(vm *v1.VirtualMachine, name string, labels []string) error {
+	set, remove, err := ParseLabels(labels)
+	if err != nil {
+		return err
+	}
+	for i := range vm.Status.Hosts {
+		host := &vm.Status.Hosts[i]
+		if host.ID != name {
+			continue
+		}
+		// the labels of the role are merged into every host of it, they are only changed by the spec of the role
+		if role := vm.GetHostByRole(host.Role); role != nil {
+			for _, k := range remove {
+				if _, ok := role.Labels[k]; ok {
+					return fmt.Errorf("label %s of host %s is inherited from role %s, it can not be removed from the host", k, name, role.Role)
+				}
+			}
+		}
+		if host.Labels == nil {
+			host.Labels = make(map[string]string)
+		}
+		for k, v := range set {
+			host.Labels[k] = v
+		}
+		for _, k := range remove {
+			delete(host.Labels, k)
+		}
+		logger.Info("host %s labels: %v", name, vm.GetHostLabels(host))
+		return yaml.MarshalYamlToFile(configs.VirtualMachineFilePath(vm.Name), vm)
+	}
+	return fmt.Errorf("host %s not found in cluster %s", name, vm.Name)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_labelHost_inherited(t *testing.T) {
+	vm := newTestVM()
+	vm.Spec.Hosts[1].Labels = map[string]string{"etcd": "true"}
+	vm.Status.Hosts[1].Labels = map[string]string{"etcd": "false", "gpu": "true"}
+	err := labelHost(vm, "default-node-0", []string{"gpu-", "etcd-"})
+	if err == nil || !strings.Contains(err.Error(), "inherited from role node") {
+		t.Fatalf("labelHost() error = %v, want the label is inherited from role node", err)
+	}
+	if got := vm.GetHostLabels(&vm.Status.Hosts[1]); got["gpu"] != "true" || got["etcd"] != "false" {
+		t.Errorf("labelHost() changed the labels to %v after the error", got)
+	}
+}
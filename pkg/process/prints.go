@@ -99,6 +99,10 @@ func inspectHostname(vm *v1.VirtualMachine, hostname string) {
 					Name: "Used",
 					Info: h.Used,
 				})
+				tables = append(tables, printTable{
+					Name: "Labels",
+					Info: vm.GetHostLabels(&h),
+				})
 			}
 		}
 	}
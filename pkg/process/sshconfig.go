@@ -0,0 +1,193 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/configs"
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+)
+
+const (
+	// orbSSHAddress and orbSSHPort are the address of the ssh proxy of orb, it logins the machine
+	// by the user like root@default-node-0.
+	orbSSHAddress = "127.0.0.1"
+	orbSSHPort    = 32222
+	// orbIdentityFile is the key of the ssh proxy of orb relative to the home dir.
+	orbIdentityFile = ".orbstack/ssh/id_ed25519"
+	// sshConfigInclude is the line included the ssh config files of the clusters in ~/.ssh/config.
+	sshConfigInclude = "Include sealvm/*.conf"
+)
+
+// sshDir returns the ssh dir of the current user, it is a var for testing.
+var sshDir = defaultSSHDir
+
+func defaultSSHDir() string {
+	return filepath.Join(fileutil.GetHomeDir(), ".ssh")
+}
+
+// SSHConfigFilePath is the ssh config file of the cluster installed by InstallSSHConfig.
+func SSHConfigFilePath(clusterName string) string {
+	return filepath.Join(sshDir(), "sealvm", clusterName+".conf")
+}
+
+// SSHConfig returns the OpenSSH config of the hosts of the vm, the Host of every host is its name
+// like default-node-0. The orb hosts are connected by the ssh proxy of orb, the others by their first ip.
+func SSHConfig(vm *v1.VirtualMachine) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# generated by sealvm for cluster %s, do not edit\n", vm.Name)
+	user := vm.Spec.SSH.User
+	if user == "" {
+		user = "root"
+	}
+	for _, host := range sortedHosts(vm) {
+		if orb, ok := orbSSH(vm, host); ok {
+			fmt.Fprintf(&b, "\nHost %s\n", host.ID)
+			writeOption(&b, "HostName", orbSSHAddress)
+			writeOption(&b, "Port", strconv.Itoa(orb.Port))
+			writeOption(&b, "User", orb.User)
+			writeOption(&b, "IdentityFile", orb.PkFile)
+			writeOption(&b, "IdentityFile", vm.Spec.SSH.PkFile)
+			if orb.ForwardAgent {
+				writeOption(&b, "ForwardAgent", "yes")
+			}
+			continue
+		}
+		addr, err := SSHAddress(host)
+		if err != nil {
+			logger.Warn("skip the ssh config of host %s: %v", host.ID, err)
+			continue
+		}
+		fmt.Fprintf(&b, "\nHost %s\n", host.ID)
+		writeOption(&b, "HostName", addr)
+		writeOption(&b, "User", user)
+		if vm.Spec.SSH.Port != 0 {
+			writeOption(&b, "Port", strconv.Itoa(vm.Spec.SSH.Port))
+		}
+		writeOption(&b, "IdentityFile", vm.Spec.SSH.PkFile)
+		writeOption(&b, "CertificateFile", vm.Spec.SSH.CertFile)
+		writeOption(&b, "ProxyJump", vm.Spec.SSH.ProxyJump)
+		if vm.Spec.SSH.ForwardAgent {
+			writeOption(&b, "ForwardAgent", "yes")
+		}
+		writeOption(&b, "UserKnownHostsFile", configs.KnownHostsFilePath(vm.Name))
+	}
+	return b.String()
+}
+
+// EtcHosts returns the /etc/hosts entries of the hosts of the vm, the first ip and the name of every host.
+func EtcHosts(vm *v1.VirtualMachine) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# generated by sealvm for cluster %s\n", vm.Name)
+	for _, host := range sortedHosts(vm) {
+		addr, err := SSHAddress(host)
+		if err != nil {
+			logger.Warn("skip the hosts entry of host %s: %v", host.ID, err)
+			continue
+		}
+		fmt.Fprintf(&b, "%s\t%s\n", addr, host.ID)
+	}
+	return b.String()
+}
+
+// InstallSSHConfig writes the ssh config of the vm to SSHConfigFilePath and includes it in ~/.ssh/config,
+// so the hosts can be connected by ssh, scp and the other tools by their names.
+func InstallSSHConfig(vm *v1.VirtualMachine) (string, error) {
+	file := SSHConfigFilePath(vm.Name)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return "", err
+	}
+	if err := fileutil.AtomicWriteFile(file, []byte(SSHConfig(vm)), 0600); err != nil {
+		return "", fmt.Errorf("failed to write ssh config %s: %v", file, err)
+	}
+	return file, includeSSHConfig()
+}
+
+// UninstallSSHConfig removes the ssh config file of the cluster, the Include in ~/.ssh/config is kept
+// for the other clusters.
+func UninstallSSHConfig(clusterName string) error {
+	if err := os.Remove(SSHConfigFilePath(clusterName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// includeSSHConfig adds the Include of the ssh config files of the clusters to the head of ~/.ssh/config.
+// It must be before any Host or Match, otherwise the included hosts only match in that block.
+func includeSSHConfig() error {
+	file := filepath.Join(sshDir(), "config")
+	// keep the link of the config managed by the dotfiles
+	if target, err := filepath.EvalSymlinks(file); err == nil {
+		file = target
+	}
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == sshConfigInclude {
+			return nil
+		}
+	}
+	content := fmt.Sprintf("# added by sealvm\n%s\n\n%s", sshConfigInclude, data)
+	if err = fileutil.AtomicWriteFile(file, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to include the ssh config of sealvm in %s: %v", file, err)
+	}
+	logger.Info("added %q to %s", sshConfigInclude, file)
+	return nil
+}
+
+// sortedHosts returns the hosts of the vm sorted by name.
+func sortedHosts(vm *v1.VirtualMachine) []*v1.VirtualMachineHostStatus {
+	hosts := make([]*v1.VirtualMachineHostStatus, 0, len(vm.Status.Hosts))
+	for i := range vm.Status.Hosts {
+		hosts = append(hosts, &vm.Status.Hosts[i])
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].ID < hosts[j].ID
+	})
+	return hosts
+}
+
+// isOrbHost returns true if the host is an orb machine, its ips contain the orb host name.
+func isOrbHost(host *v1.VirtualMachineHostStatus) bool {
+	for _, ip := range host.IPs {
+		if strings.HasSuffix(ip, orbHostSuffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOption writes the option of ssh config if the value is not empty, the value with spaces is quoted.
+func writeOption(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	if strings.ContainsAny(value, " \t") {
+		value = `"` + value + `"`
+	}
+	fmt.Fprintf(b, "    %s %s\n", key, value)
+}
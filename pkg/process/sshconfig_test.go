@@ -0,0 +1,86 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fileutil "github.com/labring/sealvm/pkg/utils/file"
+)
+
+func TestSSHConfig(t *testing.T) {
+	vm := newTestVM()
+	vm.Spec.SSH.PkFile = "/root/.ssh/id_rsa"
+	vm.Spec.SSH.Port = 2222
+	got := SSHConfig(vm)
+	for _, want := range []string{
+		"Host default-master-0\n    HostName 127.0.0.1\n    Port 32222\n    User root@default-master-0\n" +
+			"    IdentityFile " + filepath.Join(fileutil.GetHomeDir(), orbIdentityFile) + "\n    IdentityFile /root/.ssh/id_rsa\n",
+		"Host default-node-0\n    HostName 192.168.64.3\n    User root\n    Port 2222\n    IdentityFile /root/.ssh/id_rsa\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SSHConfig() = %s, want it contains %s", got, want)
+		}
+	}
+	if strings.Contains(got, "Host default-node-1") {
+		t.Errorf("SSHConfig() = %s, want the host without ip skipped", got)
+	}
+}
+
+func TestEtcHosts(t *testing.T) {
+	got := EtcHosts(newTestVM())
+	want := "# generated by sealvm for cluster default\n192.168.64.2\tdefault-master-0\n192.168.64.3\tdefault-node-0\n"
+	if got != want {
+		t.Errorf("EtcHosts() = %q, want %q", got, want)
+	}
+}
+
+func TestInstallSSHConfig(t *testing.T) {
+	dir := t.TempDir()
+	sshDir = func() string { return dir }
+	defer func() { sshDir = defaultSSHDir }()
+	config := filepath.Join(dir, "config")
+	if err := os.WriteFile(config, []byte("Host *\n    ServerAliveInterval 30\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	vm := newTestVM()
+	for i := 0; i < 2; i++ {
+		file, err := InstallSSHConfig(vm)
+		if err != nil {
+			t.Fatalf("InstallSSHConfig() error = %v", err)
+		}
+		if file != filepath.Join(dir, "sealvm", "default.conf") || !fileutil.IsFile(file) {
+			t.Errorf("InstallSSHConfig() = %s, want the file in %s", file, dir)
+		}
+	}
+	data, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# added by sealvm\nInclude sealvm/*.conf\n\nHost *\n    ServerAliveInterval 30\n"; string(data) != want {
+		t.Errorf("ssh config = %q, want %q", data, want)
+	}
+	if err = UninstallSSHConfig(vm.Name); err != nil {
+		t.Fatalf("UninstallSSHConfig() error = %v", err)
+	}
+	if fileutil.IsExist(SSHConfigFilePath(vm.Name)) {
+		t.Errorf("UninstallSSHConfig() does not remove %s", SSHConfigFilePath(vm.Name))
+	}
+}
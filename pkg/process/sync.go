@@ -0,0 +1,374 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/labring/sealvm/pkg/ssh"
+	"github.com/labring/sealvm/pkg/utils/ignore"
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"golang.org/x/sync/errgroup"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// defaultSyncExcludes are always ignored by sync besides the .gitignore.
+var defaultSyncExcludes = []string{".git/"}
+
+// SyncOptions are the options of Sync.
+type SyncOptions struct {
+	// Watch keeps pushing the changes of the local dir until ctx is done
+	Watch bool
+	// Delete removes the remote files which do not exist in the local dir at the first sync
+	Delete bool
+	// Excludes are the extra patterns ignored like the lines of .gitignore
+	Excludes []string
+	// Interval is the interval of checking the local dir for changes when it can not be watched,
+	// and of pushing the changes again after a failed push
+	Interval time.Duration
+	// Debounce is how long the local dir must be unchanged before the changes are pushed
+	Debounce time.Duration
+}
+
+// fileState is the state of a local file compared to find the changes.
+type fileState struct {
+	dir     bool
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// Sync copies the contents of the local dir src to the remote dir of the hosts, dst is <target>:<path> and
+// the files ignored by the .gitignore of src are skipped. With Watch, the changed and deleted files are
+// pushed to the hosts until ctx is done, the directories which are not ignored are watched by fsnotify and
+// src is checked every Interval only when watching is not available.
+func Sync(ctx context.Context, vm *v1.VirtualMachine, src, dst string, opts SyncOptions) error {
+	target, remotePath, ok := parseRemotePath(dst)
+	if !ok || remotePath == "" {
+		return fmt.Errorf("%s must be a remote path like node:/root/sealos", dst)
+	}
+	if opts.Watch && opts.Interval <= 0 {
+		return fmt.Errorf("the interval of watching %s must be positive", src)
+	}
+	if info, err := os.Stat(src); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", src)
+	}
+	hosts, err := ResolveTargets(vm, target)
+	if err != nil {
+		return err
+	}
+	matcher, err := loadSyncIgnore(src, opts.Excludes)
+	if err != nil {
+		return err
+	}
+	clients, err := newHostClients(vm, hosts)
+	if err != nil {
+		return err
+	}
+	defer closeClients(clients)
+
+	last, err := snapshot(src, matcher)
+	if err != nil {
+		return err
+	}
+	err = forEachHost(hosts, func(host *v1.VirtualMachineHostStatus) error {
+		client := clients[host.ID]
+		result, err := client.Sync(client.addr, src, remotePath, ssh.SyncOptions{Delete: opts.Delete, Ignore: matcher.Ignored, Progress: "syncing files to " + host.ID})
+		if err != nil {
+			return err
+		}
+		logger.Info("%s: sync %s to %s: %s", host.ID, src, remotePath, result)
+		return nil
+	})
+	if err != nil || !opts.Watch {
+		return err
+	}
+
+	logger.Info("watching %s for changes, press Ctrl+C to stop", src)
+	var (
+		events  <-chan fsnotify.Event
+		errs    <-chan error
+		tick    <-chan time.Time
+		settled <-chan time.Time
+	)
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = watchDirs(watcher, src, src, matcher); err != nil {
+			_ = watcher.Close()
+		}
+	}
+	if err != nil {
+		logger.Warn("failed to watch %s, checking it for changes every %s: %v", src, opts.Interval, err)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	} else {
+		defer watcher.Close()
+		events, errs = watcher.Events, watcher.Errors
+	}
+	pushed := last
+	// push pushes the changes since the last push to the hosts, it returns false when any host fails
+	push := func() bool {
+		current, err := snapshot(src, matcher)
+		if err != nil {
+			logger.Warn("failed to check %s for changes: %v", src, err)
+			return false
+		}
+		changed, deleted := diffSnapshots(pushed, current)
+		// the files ignored by the new patterns are kept on the hosts, they are not deleted locally
+		kept := deleted[:0]
+		for _, rel := range deleted {
+			if !matcher.Ignored(rel, pushed[rel].dir) {
+				kept = append(kept, rel)
+			}
+		}
+		deleted = kept
+		if len(changed) == 0 && len(deleted) == 0 {
+			pushed = current
+			return true
+		}
+		err = forEachHost(hosts, func(host *v1.VirtualMachineHostStatus) error {
+			client := clients[host.ID]
+			result, err := client.Push(client.addr, src, remotePath, changed, deleted)
+			if err != nil {
+				return err
+			}
+			logger.Info("%s: push %s: %d uploaded, %d deleted", host.ID, remotePath, len(result.Uploaded), len(result.Deleted))
+			return nil
+		})
+		if err != nil {
+			// pushing to the other hosts again is harmless
+			logger.Warn("failed to push the changes of %s, retrying: %v", src, err)
+			return false
+		}
+		pushed = current
+		return true
+	}
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			rel, err := filepath.Rel(src, event.Name)
+			if err != nil {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			if rel == ".gitignore" {
+				if matcher, err = loadSyncIgnore(src, opts.Excludes); err != nil {
+					logger.Warn("failed to load the ignore patterns of %s: %v", src, err)
+					continue
+				}
+				// the directories which are not ignored any more are watched
+				if err = watchDirs(watcher, src, src, matcher); err != nil {
+					logger.Warn("failed to watch %s: %v", src, err)
+				}
+			}
+			info, statErr := os.Lstat(event.Name)
+			dir := statErr == nil && info.IsDir()
+			if matcher.Ignored(rel, dir) {
+				continue
+			}
+			if dir && event.Op&fsnotify.Create != 0 {
+				if err = watchDirs(watcher, src, event.Name, matcher); err != nil {
+					logger.Warn("failed to watch %s: %v", event.Name, err)
+				}
+			}
+			settled = time.After(opts.Debounce)
+		case err := <-errs:
+			logger.Warn("failed to watch %s: %v", src, err)
+		case <-settled:
+			settled = nil
+			if !push() {
+				// push the changes again after the interval
+				settled = time.After(opts.Interval)
+			}
+		case <-tick:
+			if matcher, err = loadSyncIgnore(src, opts.Excludes); err != nil {
+				logger.Warn("failed to load the ignore patterns of %s: %v", src, err)
+				continue
+			}
+			current, err := snapshot(src, matcher)
+			if err != nil {
+				logger.Warn("failed to check %s for changes: %v", src, err)
+				continue
+			}
+			if !equalSnapshots(current, last) {
+				last, changedAt = current, time.Now()
+				continue
+			}
+			if changedAt.IsZero() || time.Since(changedAt) < opts.Debounce {
+				continue
+			}
+			changedAt = time.Time{}
+			if !push() {
+				// push the changes again after the next debounce
+				changedAt = time.Now()
+			}
+		}
+	}
+}
+
+// watchDirs adds the directory dir in src and its directories to the watcher except the ignored ones,
+// the changes in the ignored directories are never pushed.
+func watchDirs(watcher *fsnotify.Watcher, src, dir string, matcher *ignore.Matcher) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// the directory is removed during the walk, its removal is an event of its parent
+			if os.IsNotExist(err) && p != src {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != src {
+			rel, err := filepath.Rel(src, p)
+			if err != nil {
+				return err
+			}
+			if matcher.Ignored(filepath.ToSlash(rel), true) {
+				return filepath.SkipDir
+			}
+		}
+		return watcher.Add(p)
+	})
+}
+
+// loadSyncIgnore returns the matcher of the .gitignore of src, the default excludes and the excludes.
+func loadSyncIgnore(src string, excludes []string) (*ignore.Matcher, error) {
+	return ignore.Load(src, append(append([]string{}, defaultSyncExcludes...), excludes...)...)
+}
+
+// forEachHost runs fn for the hosts in parallel and returns the errors of all the hosts.
+func forEachHost(hosts []*v1.VirtualMachineHostStatus, fn func(host *v1.VirtualMachineHostStatus) error) error {
+	var (
+		lock   sync.Mutex
+		errArr = make([]error, 0)
+	)
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, host := range hosts {
+		host := host
+		eg.Go(func() error {
+			if err := fn(host); err != nil {
+				lock.Lock()
+				errArr = append(errArr, fmt.Errorf("%s: %v", host.ID, err))
+				lock.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	return utilerrors.NewAggregate(errArr)
+}
+
+// snapshot returns the states of the files and directories in src except the ignored ones by the slash
+// separated paths relative to src. Only regular files and directories are synced.
+func snapshot(src string, matcher *ignore.Matcher) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// the file is removed during the walk, it is found by the next check
+			if os.IsNotExist(err) && p != src {
+				return nil
+			}
+			return err
+		}
+		if p == src {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matcher.Ignored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		states[rel] = fileState{dir: info.IsDir(), size: info.Size(), mode: info.Mode().Perm(), modTime: info.ModTime()}
+		return nil
+	})
+	return states, err
+}
+
+// equalSnapshots returns true if nothing is changed between the snapshots.
+func equalSnapshots(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for rel, s := range a {
+		if t, ok := b[rel]; !ok || !s.modTime.Equal(t.modTime) || s.dir != t.dir || s.size != t.size || s.mode != t.mode {
+			return false
+		}
+	}
+	return true
+}
+
+// diffSnapshots returns the paths created or modified and the paths deleted from the old snapshot to the new one.
+// A path changed between a file and a directory is both deleted and changed.
+func diffSnapshots(old, current map[string]fileState) (changed, deleted []string) {
+	for rel, s := range current {
+		o, ok := old[rel]
+		if ok && o.dir != s.dir {
+			deleted = append(deleted, rel)
+		}
+		if !ok || o.dir != s.dir || !o.modTime.Equal(s.modTime) || o.size != s.size || o.mode != s.mode {
+			// the mtime of a directory changes when its files are created or deleted, they are pushed by themselves
+			if ok && o.dir && s.dir && o.mode == s.mode {
+				continue
+			}
+			changed = append(changed, rel)
+		}
+	}
+	for rel := range old {
+		if _, ok := current[rel]; !ok {
+			deleted = append(deleted, rel)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(deleted)
+	return changed, deleted
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func Test_snapshot(t *testing.T) {
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":     "_output/\n*.log\n",
+		"main.go":        "package main",
+		"pkg/a.go":       "package pkg",
+		"pkg/a.log":      "log",
+		"_output/sealos": "bin",
+		".git/HEAD":      "ref",
+		"hack/tmp.sh":    "echo",
+	} {
+		p := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	matcher, err := loadSyncIgnore(src, []string{"hack/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states, err := snapshot(src, matcher)
+	if err != nil {
+		t.Fatalf("snapshot() error = %v", err)
+	}
+	got := make([]string, 0, len(states))
+	for rel := range states {
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	if want := []string{".gitignore", "main.go", "pkg", "pkg/a.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot() = %v, want %v", got, want)
+	}
+}
+
+func Test_watchDirs(t *testing.T) {
+	src := t.TempDir()
+	for _, dir := range []string{"pkg/api", "_output/bin"} {
+		if err := os.MkdirAll(filepath.Join(src, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	matcher, err := loadSyncIgnore(src, []string{"_output/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Skipf("fsnotify is not available: %v", err)
+	}
+	defer watcher.Close()
+	if err = watchDirs(watcher, src, src, matcher); err != nil {
+		t.Fatalf("watchDirs() error = %v", err)
+	}
+	// the file in the ignored directory is written first, its event would be received first
+	for _, name := range []string{"_output/bin/sealos", "pkg/api/a.go"} {
+		if err = os.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := filepath.Join(src, "pkg/api/a.go")
+	select {
+	case event := <-watcher.Events:
+		if event.Name != want {
+			t.Errorf("watchDirs() first event of %s, want %s", event.Name, want)
+		}
+	case err = <-watcher.Errors:
+		t.Fatalf("watchDirs() watch error = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Errorf("watchDirs() no event of %s", want)
+	}
+}
+
+func Test_diffSnapshots(t *testing.T) {
+	now := time.Now()
+	old := map[string]fileState{
+		"pkg":      {dir: true, mode: 0755, modTime: now},
+		"pkg/a.go": {size: 1, mode: 0644, modTime: now},
+		"pkg/b.go": {size: 1, mode: 0644, modTime: now},
+		"bin":      {size: 1, mode: 0755, modTime: now},
+		"old":      {dir: true, mode: 0755, modTime: now},
+		"old/c.go": {size: 1, mode: 0644, modTime: now},
+	}
+	later := now.Add(time.Second)
+	current := map[string]fileState{
+		"pkg":      {dir: true, mode: 0755, modTime: later},
+		"pkg/a.go": {size: 1, mode: 0644, modTime: later},
+		"pkg/b.go": {size: 1, mode: 0644, modTime: now},
+		"pkg/d.go": {size: 2, mode: 0644, modTime: later},
+		"bin":      {dir: true, mode: 0755, modTime: later},
+	}
+	changed, deleted := diffSnapshots(old, current)
+	if want := []string{"bin", "pkg/a.go", "pkg/d.go"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("diffSnapshots() changed = %v, want %v", changed, want)
+	}
+	if want := []string{"bin", "old", "old/c.go"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("diffSnapshots() deleted = %v, want %v", deleted, want)
+	}
+	if changed, deleted = diffSnapshots(current, current); len(changed) != 0 || len(deleted) != 0 {
+		t.Errorf("diffSnapshots() of the same snapshot = %v, %v, want nothing", changed, deleted)
+	}
+	if !equalSnapshots(current, current) || equalSnapshots(old, current) {
+		t.Errorf("equalSnapshots() is wrong")
+	}
+}
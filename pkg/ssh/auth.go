@@ -0,0 +1,201 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// defaultAuthMethods are the auth methods when SSH.AuthMethods is empty.
+var defaultAuthMethods = []string{v1.SSHAuthPublicKey, v1.SSHAuthAgent, v1.SSHAuthPassword}
+
+// ValidateAuthMethods returns an error if any of the auth methods is not supported or is duplicated.
+func ValidateAuthMethods(methods []string) error {
+	seen := make(map[string]bool)
+	for _, m := range methods {
+		switch m {
+		case v1.SSHAuthPublicKey, v1.SSHAuthAgent, v1.SSHAuthPassword:
+		default:
+			return fmt.Errorf("ssh auth method %s is not supported, only %s", m, strings.Join(defaultAuthMethods, ","))
+		}
+		if seen[m] {
+			return fmt.Errorf("ssh auth method %s is duplicated", m)
+		}
+		seen[m] = true
+	}
+	return nil
+}
+
+// sshAuthMethod returns the auth methods in the order of AuthMethods. The keys of the private key, its
+// certificate and the agent are in one publickey method in the same order at the position of the first of
+// publickey and agent, the ssh client skips a method whose name is tried, so a second publickey method would
+// never be tried. agentConn is the connection of the agent, it is closed by the caller after the handshake.
+func (s *SSH) sshAuthMethod() (auth []ssh.AuthMethod, agentConn io.Closer) {
+	methods := s.AuthMethods
+	if len(methods) == 0 {
+		methods = defaultAuthMethods
+	}
+	var signers []ssh.Signer
+	hasPublicKey := false
+	for _, m := range methods {
+		switch m {
+		case v1.SSHAuthPublicKey:
+			signers = append(signers, s.keySigners()...)
+		case v1.SSHAuthAgent:
+			conn, agentClient := dialAgent()
+			if agentClient == nil {
+				continue
+			}
+			agentSigners, err := agentClient.Signers()
+			if err != nil {
+				logger.Debug("failed to list the keys of the ssh agent: %v", err)
+				_ = conn.Close()
+				continue
+			}
+			signers = append(signers, agentSigners...)
+			agentConn = conn
+		case v1.SSHAuthPassword:
+			if s.Password != "" {
+				auth = append(auth, ssh.Password(s.Password))
+			}
+			continue
+		default:
+			logger.Warn("ssh auth method %s is not supported, skip it", m)
+			continue
+		}
+		if !hasPublicKey {
+			hasPublicKey = true
+			auth = append(auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				return signers, nil
+			}))
+		}
+	}
+	return auth, agentConn
+}
+
+// keySigners returns the signers of the private key data and file, the certificate of the key file is before it.
+func (s *SSH) keySigners() []ssh.Signer {
+	var signers []ssh.Signer
+	if s.PkData != "" {
+		signer, err := parsePrivateKey([]byte(s.PkData), s.PkPassword)
+		if err != nil {
+			logger.Debug("failed to parse the private key data: %v", err)
+		} else {
+			signers = append(signers, signer)
+		}
+	}
+	if fileExist(s.PkFile) {
+		signer, err := parsePrivateKeyFile(s.PkFile, s.PkPassword)
+		if err != nil {
+			logger.Debug("failed to parse the private key %s: %v", s.PkFile, err)
+			return signers
+		}
+		certFile := s.CertFile
+		if certFile == "" && fileExist(s.PkFile+"-cert.pub") {
+			certFile = s.PkFile + "-cert.pub"
+		}
+		if certFile != "" {
+			certSigner, err := newCertSigner(certFile, signer)
+			if err != nil {
+				logger.Warn("failed to load the ssh certificate %s: %v", certFile, err)
+			} else {
+				signers = append(signers, certSigner)
+			}
+		}
+		signers = append(signers, signer)
+	}
+	return signers
+}
+
+// parsePrivateKeyFile parses the private key file, with the password when it is not empty.
+func parsePrivateKeyFile(pkFile, pkPassword string) (ssh.Signer, error) {
+	pkData, err := os.ReadFile(filepath.Clean(pkFile))
+	if err != nil {
+		return nil, err
+	}
+	return parsePrivateKey(pkData, pkPassword)
+}
+
+func parsePrivateKey(pkData []byte, pkPassword string) (ssh.Signer, error) {
+	if pkPassword == "" {
+		return ssh.ParsePrivateKey(pkData)
+	}
+	return ssh.ParsePrivateKeyWithPassphrase(pkData, []byte(pkPassword))
+}
+
+// newCertSigner returns the signer of the OpenSSH certificate file of the key of signer.
+func newCertSigner(certFile string, signer ssh.Signer) (ssh.Signer, error) {
+	data, err := os.ReadFile(filepath.Clean(certFile))
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("it is a %s public key, not a certificate", pub.Type())
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
+
+// dialAgent connects to the ssh agent of SSH_AUTH_SOCK, it returns nil when there is no agent.
+func dialAgent() (net.Conn, agent.ExtendedAgent) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		logger.Debug("failed to connect to the ssh agent %s: %v", sock, err)
+		return nil, nil
+	}
+	return conn, agent.NewClient(conn)
+}
+
+// forwardAgent serves the agent forwarding channels of the client by the ssh agent of SSH_AUTH_SOCK.
+func (s *SSH) forwardAgent(host string, client *ssh.Client) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		logger.Warn("[ssh][%s] SSH_AUTH_SOCK is not set, the ssh agent is not forwarded", host)
+		return
+	}
+	if err := agent.ForwardToRemote(client, sock); err != nil {
+		logger.Warn("[ssh][%s] failed to forward the ssh agent: %v", host, err)
+	}
+}
+
+// requestAgentForwarding enables the agent forwarding of the session when ForwardAgent is set.
+func (s *SSH) requestAgentForwarding(host string, session *ssh.Session) {
+	if !s.ForwardAgent || os.Getenv("SSH_AUTH_SOCK") == "" {
+		return
+	}
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		logger.Debug("[ssh][%s] failed to request agent forwarding: %v", host, err)
+	}
+}
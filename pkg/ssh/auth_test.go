@@ -0,0 +1,232 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	v1 "github.com/labring/sealvm/types/api/v1"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestValidateAuthMethods(t *testing.T) {
+	tests := []struct {
+		methods []string
+		wantErr bool
+	}{
+		{methods: nil},
+		{methods: []string{"agent", "publickey"}},
+		{methods: []string{"publickey", "agent", "password"}},
+		{methods: []string{"keyboard-interactive"}, wantErr: true},
+		{methods: []string{"agent", "agent"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.methods), func(t *testing.T) {
+			if err := ValidateAuthMethods(tt.methods); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAuthMethods() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeTestKey writes a new private key to the file and returns it.
+func writeTestKey(t *testing.T, file string) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func publicKeyOf(t *testing.T, key ed25519.PrivateKey) ssh.PublicKey {
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+// offeredKeys records the keys offered by the clients, only the allowed key is accepted.
+type offeredKeys struct {
+	lock    sync.Mutex
+	keys    []string
+	allowed func(key ssh.PublicKey) bool
+}
+
+func (o *offeredKeys) callback(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	fingerprint := ssh.FingerprintSHA256(key)
+	if len(o.keys) == 0 || o.keys[len(o.keys)-1] != fingerprint {
+		o.keys = append(o.keys, fingerprint)
+	}
+	if o.allowed(key) {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("key %s is not allowed", fingerprint)
+}
+
+// serveTestAgent serves an agent of the key on a unix socket, and sets SSH_AUTH_SOCK to it.
+func serveTestAgent(t *testing.T, key ed25519.PrivateKey) {
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = agent.ServeAgent(keyring, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+}
+
+func Test_sshAuthMethod(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test agent serves on a unix socket")
+	}
+	dir := t.TempDir()
+	pkFile := filepath.Join(dir, "id_ed25519")
+	fileKey := publicKeyOf(t, writeTestKey(t, pkFile))
+	_, agentPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentKey := publicKeyOf(t, agentPrivateKey)
+	serveTestAgent(t, agentPrivateKey)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{Key: fileKey, CertType: ssh.UserCert, ValidPrincipals: []string{"root"}, ValidBefore: ssh.CertTimeInfinity}
+	if err = cert.SignCert(rand.Reader, caSigner); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(pkFile+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0600); err != nil {
+		t.Fatal(err)
+	}
+	certChecker := &ssh.CertChecker{IsUserAuthority: func(auth ssh.PublicKey) bool {
+		return bytes.Equal(auth.Marshal(), caSigner.PublicKey().Marshal())
+	}}
+
+	tests := []struct {
+		name    string
+		methods []string
+		allowed func(key ssh.PublicKey) bool
+		// offered are the first keys offered to the server
+		offered []ssh.PublicKey
+		wantErr bool
+	}{
+		{
+			name:    "certificate",
+			allowed: func(key ssh.PublicKey) bool { _, ok := key.(*ssh.Certificate); return ok },
+			offered: []ssh.PublicKey{cert},
+		},
+		{
+			name:    "default order",
+			allowed: func(key ssh.PublicKey) bool { return bytes.Equal(key.Marshal(), agentKey.Marshal()) },
+			offered: []ssh.PublicKey{cert, fileKey, agentKey},
+		},
+		{
+			name:    "agent first",
+			methods: []string{v1.SSHAuthAgent, v1.SSHAuthPublicKey},
+			allowed: func(key ssh.PublicKey) bool { return bytes.Equal(key.Marshal(), fileKey.Marshal()) },
+			offered: []ssh.PublicKey{agentKey, cert, fileKey},
+		},
+		{
+			name:    "keys before password",
+			methods: []string{v1.SSHAuthPublicKey, v1.SSHAuthPassword, v1.SSHAuthAgent},
+			allowed: func(key ssh.PublicKey) bool { return bytes.Equal(key.Marshal(), agentKey.Marshal()) },
+			offered: []ssh.PublicKey{cert, fileKey, agentKey},
+		},
+		{
+			name:    "agent only",
+			methods: []string{v1.SSHAuthAgent},
+			allowed: func(key ssh.PublicKey) bool { return bytes.Equal(key.Marshal(), fileKey.Marshal()) },
+			offered: []ssh.PublicKey{agentKey},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offered := &offeredKeys{allowed: tt.allowed}
+			server := newTestServerWithConfig(t, &ssh.ServerConfig{
+				PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+					if c, ok := key.(*ssh.Certificate); ok {
+						if _, err := certChecker.Authenticate(conn, c); err != nil {
+							return nil, err
+						}
+					}
+					return offered.callback(conn, key)
+				},
+			})
+			s := &SSH{User: "root", PkFile: pkFile, AuthMethods: tt.methods}
+			client, err := s.connect(server.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("connect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if client != nil {
+				_ = client.Close()
+			}
+			offered.lock.Lock()
+			defer offered.lock.Unlock()
+			for i, key := range tt.offered {
+				if i >= len(offered.keys) || offered.keys[i] != ssh.FingerprintSHA256(key) {
+					t.Fatalf("offered keys = %v, want %s at %d", offered.keys, ssh.FingerprintSHA256(key), i)
+				}
+			}
+		})
+	}
+}
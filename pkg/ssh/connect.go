@@ -16,10 +16,8 @@ package ssh
 
 import (
 	"fmt"
-	"net"
 	"os"
 	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -32,125 +30,71 @@ import (
 
 // SSH connection operation
 func (s *SSH) connect(host string) (*ssh.Client, error) {
-	auth := s.sshAuthMethod(s.Password, s.PkFile, s.PkData, s.PkPassword)
+	auth, agentConn := s.sshAuthMethod()
+	if agentConn != nil {
+		// the agent signs during the handshake only, the forwarded agents dial their own connections
+		defer agentConn.Close()
+	}
 	config := ssh.Config{
 		Ciphers: []string{"aes128-ctr", "aes192-ctr", "aes256-ctr", "aes128-gcm@openssh.com", "arcfour256", "arcfour128", "aes128-cbc", "3des-cbc", "aes192-cbc", "aes256-cbc"},
 	}
-	DefaultTimeout := time.Duration(15) * time.Second
-	if s.Timeout == nil {
-		s.Timeout = &DefaultTimeout
+	timeout := time.Duration(15) * time.Second
+	if s.Timeout != nil {
+		timeout = *s.Timeout
 	}
 	clientConfig := &ssh.ClientConfig{
-		User:    s.User,
-		Auth:    auth,
-		Timeout: *s.Timeout,
-		Config:  config,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		User:            s.User,
+		Auth:            auth,
+		Timeout:         timeout,
+		Config:          config,
+		HostKeyCallback: hostKeyCallback(s.KnownHostsFile),
 	}
-	ip, port := iputils.GetSSHHostIPAndPort(host)
-	addr := s.addrReformat(ip, port)
-	return ssh.Dial("tcp", addr, clientConfig)
-}
-
-func (s *SSH) Connect(host string) (*ssh.Client, *ssh.Session, error) {
-	client, err := s.connect(host)
+	jumps, err := parseProxyJump(s.ProxyJump)
 	if err != nil {
-		return nil, nil, err
-	}
-
-	session, err := client.NewSession()
-	if err != nil {
-		_ = client.Close()
-		return nil, nil, err
-	}
-
-	modes := ssh.TerminalModes{
-		ssh.ECHO:          0,     //disable echoing
-		ssh.TTY_OP_ISPEED: 14400, // input speed = 14.4kbaud
-		ssh.TTY_OP_OSPEED: 14400, // output speed = 14.4kbaud
-	}
-
-	if err := session.RequestPty("xterm", 80, 40, modes); err != nil {
-		_ = session.Close()
-		_ = client.Close()
-		return nil, nil, err
-	}
-
-	return client, session, nil
-}
-
-func (s *SSH) sshAuthMethod(password, pkFile, pkData, pkPasswd string) (auth []ssh.AuthMethod) {
-	if pkData != "" {
-		am, err := s.sshPrivateKeyDataMethod(pkData, pkPasswd)
-		if err == nil {
-			auth = append(auth, am)
-		}
-	}
-	if fileExist(pkFile) {
-		am, err := s.sshPrivateKeyMethod(pkFile, pkPasswd)
-		if err == nil {
-			auth = append(auth, am)
-		}
-	}
-	if password != "" {
-		auth = append(auth, s.sshPasswordMethod(password))
+		return nil, err
 	}
-	return auth
-}
-
-// Authentication with a private key,private key has password and no password to verify in this
-func (s *SSH) sshPrivateKeyMethod(pkFile, pkPassword string) (am ssh.AuthMethod, err error) {
-	pkData, err := os.ReadFile(filepath.Clean(pkFile))
+	client, err := dialJump(jumps, s.hostAddr(host), clientConfig)
 	if err != nil {
 		return nil, err
 	}
-
-	var pk ssh.Signer
-	if pkPassword == "" {
-		pk, err = ssh.ParsePrivateKey(pkData)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		bufPwd := []byte(pkPassword)
-		pk, err = ssh.ParsePrivateKeyWithPassphrase(pkData, bufPwd)
-		if err != nil {
-			return nil, err
-		}
+	if s.ForwardAgent {
+		s.forwardAgent(host, client)
 	}
-	return ssh.PublicKeys(pk), nil
+	return client, nil
 }
 
-// Authentication with a private key,private key has password and no password to verify in this
-func (s *SSH) sshPrivateKeyDataMethod(pkData, pkPassword string) (am ssh.AuthMethod, err error) {
+// newSession opens a session on the connection of the host, done closes the session and frees it for the
+// other sessions of the host. It has no pty, so the stderr of the command is not merged into the stdout.
+func (s *SSH) newSession(host string) (session *ssh.Session, done func(), err error) {
+	release, err := s.open(host, func(client *ssh.Client) error {
+		session, err = client.NewSession()
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	var pk ssh.Signer
-	if pkPassword == "" {
-		pk, err = ssh.ParsePrivateKey([]byte(pkData))
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		bufPwd := []byte(pkPassword)
-		pk, err = ssh.ParsePrivateKeyWithPassphrase([]byte(pkData), bufPwd)
-		if err != nil {
-			return nil, err
-		}
+	done = func() {
+		_ = session.Close()
+		release()
 	}
-	return ssh.PublicKeys(pk), nil
+	s.requestAgentForwarding(host, session)
+
+	return session, done, nil
 }
 
 func fileExist(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil || os.IsExist(err)
 }
-func (s *SSH) sshPasswordMethod(password string) ssh.AuthMethod {
-	return ssh.Password(password)
+
+// hostAddr returns the address of the host with the port, Port or 22 when the host has no port.
+func (s *SSH) hostAddr(host string) string {
+	port := "22"
+	if s.Port > 0 {
+		port = strconv.Itoa(s.Port)
+	}
+	ip, port := iputils.GetHostIPAndPortOrDefault(host, port)
+	return s.addrReformat(ip, port)
 }
 
 func (s *SSH) addrReformat(host, port string) string {
@@ -179,3 +123,8 @@ func (s *SSH) remoteFileExist(host, remoteFilePath string) bool {
 	}
 	return count != 0
 }
+
+func (s *SSH) IsRemoteDir(host, remoteFilePath string) bool {
+	_, err := s.Cmd(host, fmt.Sprintf("test -d %s", shellQuote(remoteFilePath)))
+	return err == nil
+}
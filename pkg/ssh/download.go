@@ -0,0 +1,121 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"github.com/pkg/sftp"
+)
+
+// Download copies the remote file or directory to localPath recursively, the modes of the files and
+// directories are the same as the remote ones. When remotePath is a directory, localPath is the directory
+// of its contents, otherwise localPath is the path of the file. Only regular files and directories are copied.
+func (s *SSH) Download(host, remotePath, localPath string) (int, error) {
+	_, sftpClient, done, err := s.sftpConnect(host)
+	if err != nil {
+		return 0, fmt.Errorf("new sftp client failed %s", err)
+	}
+	defer done()
+	count, err := download(sftpClient, remotePath, localPath)
+	if err != nil {
+		return count, fmt.Errorf("[ssh][%s] %v", host, err)
+	}
+	return count, nil
+}
+
+// download copies the remote file or directory of the sftp client to localPath, see Download.
+func download(client *sftp.Client, remotePath, localPath string) (int, error) {
+	info, err := client.Stat(remotePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %v", remotePath, err)
+	}
+	if !info.IsDir() {
+		if err = os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			return 0, err
+		}
+		if err = downloadFile(client, remotePath, localPath, info.Mode().Perm()); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	count := 0
+	// the modes of the directories are set at last, a read-only directory can not be written into
+	dirs, modes := make([]string, 0), make([]os.FileMode, 0)
+	walker := client.Walk(remotePath)
+	for walker.Step() {
+		if err = walker.Err(); err != nil {
+			return count, fmt.Errorf("failed to list %s: %v", walker.Path(), err)
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), path.Clean(remotePath)), "/")
+		local := filepath.Join(localPath, filepath.FromSlash(rel))
+		stat := walker.Stat()
+		switch {
+		case stat.IsDir():
+			if err = os.MkdirAll(local, 0755); err != nil {
+				return count, err
+			}
+			dirs = append(dirs, local)
+			modes = append(modes, stat.Mode().Perm())
+		case stat.Mode().IsRegular():
+			if err = downloadFile(client, walker.Path(), local, stat.Mode().Perm()); err != nil {
+				return count, err
+			}
+			count++
+		default:
+			logger.Debug("skip %s, it is neither a regular file nor a directory", walker.Path())
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err = os.Chmod(dirs[i], modes[i]); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// downloadFile writes the remote file to a temporary file and renames it to the local path.
+func downloadFile(client *sftp.Client, remotePath, localPath string, mode os.FileMode) error {
+	srcFile, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", remotePath, err)
+	}
+	defer srcFile.Close()
+	tmp := filepath.Join(filepath.Dir(localPath), "."+filepath.Base(localPath)+".sealvm-tmp")
+	dstFile, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dstFile, srcFile); err != nil {
+		_ = dstFile.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to download %s: %v", remotePath, err)
+	}
+	if err = dstFile.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, localPath)
+}
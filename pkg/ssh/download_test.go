@@ -0,0 +1,104 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// newPipeSftpClient returns a sftp client of a server serving the local file system in the same process.
+func newPipeSftpClient(t *testing.T) *sftp.Client {
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve()
+	}()
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// closing the server closes the pipe read by the client first, or the client waits for it forever
+		_ = server.Close()
+		_ = client.Close()
+	})
+	return client
+}
+
+func Test_download(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the sftp server serves unix paths")
+	}
+	remote := t.TempDir()
+	writeFiles(t, remote, map[string]string{"a": "a", "bin/sealos": "v1", "etc/conf.d/b.conf": "b"})
+	if err := os.Chmod(filepath.Join(remote, "bin/sealos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(remote, "etc"), 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Chmod(filepath.Join(remote, "etc"), 0755)
+	}()
+	client := newPipeSftpClient(t)
+
+	local := filepath.Join(t.TempDir(), "dst")
+	count, err := download(client, remote, local)
+	if err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+	defer func() {
+		_ = os.Chmod(filepath.Join(local, "etc"), 0755)
+	}()
+	if count != 3 {
+		t.Errorf("download() copied %d files, want 3", count)
+	}
+	for name, want := range map[string]os.FileMode{"a": 0644, "bin/sealos": 0755, "etc": 0555, "etc/conf.d/b.conf": 0644} {
+		info, err := os.Stat(filepath.Join(local, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("mode of %s = %o, want %o", name, info.Mode().Perm(), want)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(local, "bin/sealos"))
+	if err != nil || string(data) != "v1" {
+		t.Errorf("content of bin/sealos = %q, %v, want v1", data, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "single", "b.conf")
+	if count, err = download(client, filepath.Join(remote, "etc/conf.d/b.conf"), file); err != nil || count != 1 {
+		t.Fatalf("download() of a file = %d, %v", count, err)
+	}
+	if data, err = os.ReadFile(file); err != nil || string(data) != "b" {
+		t.Errorf("content of the file = %q, %v, want b", data, err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	v1 "github.com/labring/sealvm/types/api/v1"
+	"io"
 
 	"github.com/labring/sealvm/pkg/utils/logger"
 
@@ -33,12 +34,17 @@ type Exec struct {
 }
 
 func NewExecCmdFromIPs(vm *v1.VirtualMachine, ips []string) (*Exec, error) {
-	sshClient := NewSSHClient(&vm.Spec.SSH, true)
-	err := WaitSSHReady(sshClient, 6, ips...)
+	return NewExecCmdFromClient(vm, NewSSHClient(vm, true), ips)
+}
+
+// NewExecCmdFromClient is NewExecCmdFromIPs with the ssh client, the connections of the client are shared
+// with its other users and closed by its Close.
+func NewExecCmdFromClient(vm *v1.VirtualMachine, client Interface, ips []string) (*Exec, error) {
+	err := WaitSSHReady(client, 6, ips...)
 	if err != nil {
 		return nil, err
 	}
-	return &Exec{vm: vm, ipList: ips, client: sshClient}, nil
+	return &Exec{vm: vm, ipList: ips, client: client}, nil
 }
 
 func (e *Exec) RunCmd(cmd string) error {
@@ -59,6 +65,36 @@ func (e *Exec) RunCmd(cmd string) error {
 	return nil
 }
 
+// Close closes the connections of the ssh client.
+func (e *Exec) Close() error {
+	return e.client.Close()
+}
+
+// RunCmdOnce exec command on the host of ip only.
+func (e *Exec) RunCmdOnce(ip, cmd string) error {
+	return e.client.CmdAsync(ip, cmd)
+}
+
+// RunCmdStream exec command on the host of ip only, and write its logs to stdout and stderr.
+func (e *Exec) RunCmdStream(ip, cmd string, stdout, stderr io.Writer) error {
+	return e.client.CmdStream(ip, cmd, stdout, stderr)
+}
+
+// RunCmdOutput exec command on the host of ip only, and return combined standard output and standard error.
+func (e *Exec) RunCmdOutput(ip, cmd string) ([]byte, error) {
+	return e.client.Cmd(ip, cmd)
+}
+
+// RunCopyOnce copy local file to the host of ip only.
+func (e *Exec) RunCopyOnce(ip, srcFilePath, dstFilePath string) error {
+	return e.client.Copy(ip, srcFilePath, dstFilePath)
+}
+
+// RunSyncOnce copy local file to the host of ip only incrementally.
+func (e *Exec) RunSyncOnce(ip, srcFilePath, dstFilePath string, opts SyncOptions) (*SyncResult, error) {
+	return e.client.Sync(ip, srcFilePath, dstFilePath, opts)
+}
+
 func (e *Exec) RunCopy(srcFilePath, dstFilePath string) error {
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, ipAddr := range e.ipList {
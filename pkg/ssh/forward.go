@@ -0,0 +1,188 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultForwardHost is the host of the addresses of a forward without host.
+const defaultForwardHost = "127.0.0.1"
+
+// forwardRetryInterval is the interval of listening again on the host after the connection of a reverse forward is broken.
+const forwardRetryInterval = 3 * time.Second
+
+// ParseForward parses the forward like the -L and -R of ssh, listen is the address listened on
+// and target is the address connected to. The formats are port:port, port:host:port
+// and bind:port:host:port, the default bind and host are 127.0.0.1.
+func ParseForward(spec string) (listen, target string, err error) {
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 2:
+		listen, target = net.JoinHostPort(defaultForwardHost, parts[0]), net.JoinHostPort(defaultForwardHost, parts[1])
+	case 3:
+		listen, target = net.JoinHostPort(defaultForwardHost, parts[0]), net.JoinHostPort(parts[1], parts[2])
+	case 4:
+		listen, target = net.JoinHostPort(parts[0], parts[1]), net.JoinHostPort(parts[2], parts[3])
+	default:
+		return "", "", fmt.Errorf("invalid forward %s, it must be port:port, port:host:port or bind:port:host:port", spec)
+	}
+	for _, addr := range []string{listen, target} {
+		host, port, _ := net.SplitHostPort(addr)
+		if host == "" {
+			return "", "", fmt.Errorf("invalid forward %s, the host of %s is empty", spec, addr)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", "", fmt.Errorf("invalid forward %s, the port of %s must be in 1-65535", spec, addr)
+		}
+	}
+	return listen, target, nil
+}
+
+// LocalForward listens on localAddr and forwards every connection to remoteAddr through the host until
+// ctx is done, like ssh -L. It returns an error if it fails to listen on localAddr.
+func (s *SSH) LocalForward(ctx context.Context, host, localAddr, remoteAddr string) error {
+	l, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", localAddr, err)
+	}
+	stop := closeOnDone(ctx, l)
+	defer stop()
+	logger.Info("forwarding %s to %s on %s", localAddr, remoteAddr, host)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept on %s: %v", localAddr, err)
+		}
+		go func() {
+			var remote net.Conn
+			release, err := s.use(host, func(client *ssh.Client) (err error) {
+				remote, err = client.Dial("tcp", remoteAddr)
+				return err
+			})
+			if err != nil {
+				logger.Warn("[ssh][%s] failed to connect to %s: %v", host, remoteAddr, err)
+				_ = conn.Close()
+				return
+			}
+			defer release()
+			pipe(conn, remote)
+		}()
+	}
+}
+
+// RemoteForward listens on remoteAddr of the host and forwards every connection to localAddr until ctx
+// is done, like ssh -R. It listens again when the connection to the host is broken.
+func (s *SSH) RemoteForward(ctx context.Context, host, remoteAddr, localAddr string) error {
+	listened := false
+	for {
+		var l net.Listener
+		release, err := s.use(host, func(client *ssh.Client) (err error) {
+			l, err = client.Listen("tcp", remoteAddr)
+			return err
+		})
+		if err != nil {
+			if !listened {
+				return fmt.Errorf("[ssh][%s] failed to listen on %s: %v", host, remoteAddr, err)
+			}
+			logger.Warn("[ssh][%s] failed to listen on %s again: %v", host, remoteAddr, err)
+		} else {
+			if !listened {
+				logger.Info("forwarding %s on %s to %s", remoteAddr, host, localAddr)
+				listened = true
+			}
+			s.serveRemoteForward(ctx, l, localAddr)
+			release()
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(forwardRetryInterval):
+		}
+	}
+}
+
+// serveRemoteForward forwards the connections of the listener on the host to localAddr until the listener is closed.
+func (s *SSH) serveRemoteForward(ctx context.Context, l net.Listener, localAddr string) {
+	stop := closeOnDone(ctx, l)
+	defer stop()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("the reverse forward to %s is broken: %v", localAddr, err)
+			}
+			return
+		}
+		go func() {
+			local, err := net.Dial("tcp", localAddr)
+			if err != nil {
+				logger.Warn("failed to connect to %s: %v", localAddr, err)
+				_ = conn.Close()
+				return
+			}
+			pipe(conn, local)
+		}()
+	}
+}
+
+// closeOnDone closes the listener when ctx is done, stop stops waiting for ctx and closes the listener.
+func closeOnDone(ctx context.Context, l net.Listener) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = l.Close()
+	}()
+	return func() { close(done) }
+}
+
+// pipe copies the data between the connections until both directions are done, and closes them.
+func pipe(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copyConn := func(dst, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		// half close the connection so the other direction can finish
+		if c, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = c.CloseWrite()
+		} else {
+			_ = dst.Close()
+		}
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	wg.Wait()
+	_ = a.Close()
+	_ = b.Close()
+}
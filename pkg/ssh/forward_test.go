@@ -0,0 +1,123 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantListen string
+		wantTarget string
+		wantErr    bool
+	}{
+		{spec: "6443:6443", wantListen: "127.0.0.1:6443", wantTarget: "127.0.0.1:6443"},
+		{spec: "8080:10.96.0.1:80", wantListen: "127.0.0.1:8080", wantTarget: "10.96.0.1:80"},
+		{spec: "0.0.0.0:30080:localhost:30080", wantListen: "0.0.0.0:30080", wantTarget: "localhost:30080"},
+		{spec: "6443", wantErr: true},
+		{spec: "a:b:c:d:e", wantErr: true},
+		{spec: "0:6443", wantErr: true},
+		{spec: "6443:65536", wantErr: true},
+		{spec: "6443::6443", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			listen, target, err := ParseForward(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseForward() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if listen != tt.wantListen || target != tt.wantTarget {
+				t.Errorf("ParseForward() = %s, %s, want %s, %s", listen, target, tt.wantListen, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestSSH_LocalForward(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	// take a free port for the forward
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	localAddr := l.Addr().String()
+	_ = l.Close()
+
+	server := newTestServer(t)
+	pool, _ := newTestPool(t, server, defaultMaxSessions)
+	s := &SSH{pool: pool}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.LocalForward(ctx, server.addr, localAddr, echo.Addr().String())
+	}()
+
+	var conn net.Conn
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", localAddr); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("failed to connect to the forward: %v", err)
+	}
+	if _, err = conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err = io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("failed to read from the forward: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("read %q, want %q", buf, "ping")
+	}
+	_ = conn.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("LocalForward() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("LocalForward() is not stopped after ctx is done")
+	}
+}
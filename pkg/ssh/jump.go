@@ -0,0 +1,145 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os/user"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// jumpHost is a host of the ProxyJump chain.
+type jumpHost struct {
+	User string
+	Addr string
+}
+
+// ValidateProxyJump returns an error if the ProxyJump chain can not be parsed.
+func ValidateProxyJump(proxyJump string) error {
+	_, err := parseProxyJump(proxyJump)
+	return err
+}
+
+// parseProxyJump parses the jump hosts like the -J of ssh, [user@]host[:port] separated by commas.
+// The user of a host without user is the local user, the same as ssh.
+func parseProxyJump(proxyJump string) ([]jumpHost, error) {
+	if strings.TrimSpace(proxyJump) == "" {
+		return nil, nil
+	}
+	hosts := make([]jumpHost, 0)
+	for _, s := range strings.Split(proxyJump, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, fmt.Errorf("invalid proxy jump %s, a jump host is empty", proxyJump)
+		}
+		h := jumpHost{}
+		if i := strings.LastIndex(s, "@"); i >= 0 {
+			h.User, s = s[:i], s[i+1:]
+			if h.User == "" {
+				return nil, fmt.Errorf("invalid proxy jump %s, the user of %s is empty", proxyJump, s)
+			}
+		}
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			host, port = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"), "22"
+		}
+		if host == "" || strings.ContainsAny(host, "[]/ ") {
+			return nil, fmt.Errorf("invalid proxy jump %s, the host of %s is invalid", proxyJump, s)
+		}
+		h.Addr = net.JoinHostPort(host, port)
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// localUser returns the name of the current user, it is the user of the jump hosts without user.
+func localUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return "root"
+	}
+	name := u.Username
+	// the windows user name is like DOMAIN\user
+	if i := strings.LastIndex(name, `\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// dialJump connects to addr through the jump hosts in order, the connections of the jump hosts
+// are closed when the returned client is closed.
+func dialJump(jumps []jumpHost, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var (
+		clients []*ssh.Client
+		prev    *ssh.Client
+	)
+	closeAll := func() {
+		for i := len(clients) - 1; i >= 0; i-- {
+			_ = clients[i].Close()
+		}
+	}
+	for i := 0; i <= len(jumps); i++ {
+		target, targetConfig := addr, config
+		if i < len(jumps) {
+			jumpConfig := *config
+			jumpConfig.User = jumps[i].User
+			if jumpConfig.User == "" {
+				jumpConfig.User = localUser()
+			}
+			target, targetConfig = jumps[i].Addr, &jumpConfig
+		}
+		client, err := dialVia(prev, target, targetConfig)
+		if err != nil {
+			closeAll()
+			if i < len(jumps) {
+				return nil, fmt.Errorf("failed to connect to the jump host %s: %v", target, err)
+			}
+			return nil, err
+		}
+		logger.Debug("connected to %s through %d jump hosts", target, i)
+		clients = append(clients, client)
+		prev = client
+	}
+	if len(jumps) > 0 {
+		go func() {
+			_ = prev.Wait()
+			closeAll()
+		}()
+	}
+	return prev, nil
+}
+
+// dialVia connects to addr directly when via is nil, or through the connection of via.
+func dialVia(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func Test_parseProxyJump(t *testing.T) {
+	tests := []struct {
+		proxyJump string
+		want      []jumpHost
+		wantErr   bool
+	}{
+		{proxyJump: "", want: nil},
+		{proxyJump: "bastion", want: []jumpHost{{Addr: "bastion:22"}}},
+		{proxyJump: "admin@10.0.0.1:2222", want: []jumpHost{{User: "admin", Addr: "10.0.0.1:2222"}}},
+		{proxyJump: "a@bastion, b@[fd00::1]:2200", want: []jumpHost{{User: "a", Addr: "bastion:22"}, {User: "b", Addr: "[fd00::1]:2200"}}},
+		{proxyJump: "[fd00::1]", want: []jumpHost{{Addr: "[fd00::1]:22"}}},
+		{proxyJump: "bastion,", wantErr: true},
+		{proxyJump: "@bastion", wantErr: true},
+		{proxyJump: "admin@", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.proxyJump, func(t *testing.T) {
+			got, err := parseProxyJump(tt.proxyJump)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProxyJump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProxyJump() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSH_hostAddr(t *testing.T) {
+	tests := []struct {
+		port int
+		host string
+		want string
+	}{
+		{host: "192.168.64.2", want: "192.168.64.2:22"},
+		{port: 2222, host: "192.168.64.2", want: "192.168.64.2:2222"},
+		{port: 2222, host: "192.168.64.2:22", want: "192.168.64.2:22"},
+	}
+	for _, tt := range tests {
+		s := &SSH{Port: tt.port}
+		if got := s.hostAddr(tt.host); got != tt.want {
+			t.Errorf("hostAddr(%s) with port %d = %s, want %s", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestSSH_connectProxyJump(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		users []string
+	)
+	recordUser := func(conn ssh.ConnMetadata, _ ssh.PublicKey) (*ssh.Permissions, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		users = append(users, conn.User())
+		return nil, nil
+	}
+	first := newTestServerWithConfig(t, &ssh.ServerConfig{PublicKeyCallback: recordUser})
+	second := newTestServerWithConfig(t, &ssh.ServerConfig{PublicKeyCallback: recordUser})
+	target := newTestServerWithConfig(t, &ssh.ServerConfig{PublicKeyCallback: recordUser})
+
+	pkFile := filepath.Join(t.TempDir(), "id_ed25519")
+	writeTestKey(t, pkFile)
+	s := &SSH{
+		User:        "root",
+		PkFile:      pkFile,
+		AuthMethods: []string{"publickey"},
+		ProxyJump:   "jump@" + first.addr + ",hop@" + second.addr,
+	}
+	client, err := s.connect(target.addr)
+	if err != nil {
+		t.Fatalf("connect() error = %v", err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	_ = session.Close()
+	_ = client.Close()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if want := []string{"jump", "hop", "root"}; !reflect.DeepEqual(users, want) {
+		t.Errorf("users = %v, want %v", users, want)
+	}
+}
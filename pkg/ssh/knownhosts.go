@@ -0,0 +1,145 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SkipHostKeyVerify accepts any host key without the known hosts file, it is set by the --insecure flag.
+var SkipHostKeyVerify bool
+
+// knownHostsLock serializes the reads and writes of the known hosts files, the hosts are connected concurrently.
+var knownHostsLock sync.Mutex
+
+// hostKeyCallback verifies the host keys by the known hosts file. The key of a host not in the file is trusted
+// and recorded on the first connection, a key different from the recorded one is an error.
+func hostKeyCallback(file string) ssh.HostKeyCallback {
+	if SkipHostKeyVerify || file == "" {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsLock.Lock()
+		defer knownHostsLock.Unlock()
+		if !fileExist(file) {
+			if err := writeKnownHosts(file, nil); err != nil {
+				return err
+			}
+		}
+		callback, err := knownhosts.New(file)
+		if err != nil {
+			return fmt.Errorf("failed to load known hosts %s: %v", file, err)
+		}
+		err = callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if err == nil || !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) == 0 {
+			logger.Debug("record the %s host key %s of %s to %s", key.Type(), ssh.FingerprintSHA256(key), hostname, file)
+			return appendKnownHost(file, hostname, key)
+		}
+		want := keyErr.Want[0]
+		return fmt.Errorf("host key of %s has changed, it is %s %s but %s %s is recorded at %s:%d. "+
+			"If the vm is rebuilt, remove the line or connect with --insecure",
+			hostname, key.Type(), ssh.FingerprintSHA256(key), want.Key.Type(), ssh.FingerprintSHA256(want.Key), want.Filename, want.Line)
+	}
+}
+
+func appendKnownHost(file, hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func writeKnownHosts(file string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	data := ""
+	if len(lines) > 0 {
+		data = strings.Join(lines, "\n") + "\n"
+	}
+	return os.WriteFile(file, []byte(data), 0600)
+}
+
+// RemoveKnownHosts removes the keys of the hosts from the known hosts file on any port, the keys of
+// the hosts deleted or rebuilt are removed so the new hosts on the same addresses are trusted again.
+func RemoveKnownHosts(file string, hosts ...string) error {
+	knownHostsLock.Lock()
+	defer knownHostsLock.Unlock()
+	if !fileExist(file) || len(hosts) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return err
+	}
+	lines := make([]string, 0)
+	removed := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		if knownHostMatches(line, hosts) {
+			removed++
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if removed == 0 {
+		return nil
+	}
+	logger.Debug("remove %d host keys of %v from %s", removed, hosts, file)
+	return writeKnownHosts(file, lines)
+}
+
+// knownHostMatches returns true if any host of the line is one of hosts, with or without a port.
+func knownHostMatches(line string, hosts []string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+		return false
+	}
+	patterns := fields[0]
+	if strings.HasPrefix(patterns, "@") && len(fields) > 2 {
+		patterns = fields[1]
+	}
+	for _, pattern := range strings.Split(patterns, ",") {
+		for _, host := range hosts {
+			if pattern == host || strings.HasPrefix(pattern, "["+host+"]:") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func Test_hostKeyCallback(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "default", "known_hosts")
+	callback := hostKeyCallback(file)
+	key, otherKey := newTestHostKey(t), newTestHostKey(t)
+	addr := &net.TCPAddr{IP: net.ParseIP("192.168.64.2"), Port: 22}
+
+	if err := callback("192.168.64.2:22", addr, key); err != nil {
+		t.Fatalf("the key of an unknown host is not trusted: %v", err)
+	}
+	if err := callback("192.168.64.2:22", addr, key); err != nil {
+		t.Fatalf("the recorded key is not accepted: %v", err)
+	}
+	err := callback("192.168.64.2:22", addr, otherKey)
+	if err == nil || !strings.Contains(err.Error(), "host key of 192.168.64.2:22 has changed") {
+		t.Fatalf("a changed key is accepted, error = %v", err)
+	}
+	otherAddr := &net.TCPAddr{IP: net.ParseIP("192.168.64.3"), Port: 22}
+	if err = callback("192.168.64.3:22", otherAddr, otherKey); err != nil {
+		t.Fatalf("the key of another host is not trusted: %v", err)
+	}
+
+	if err = RemoveKnownHosts(file, "192.168.64.2"); err != nil {
+		t.Fatalf("RemoveKnownHosts() error = %v", err)
+	}
+	if err = callback("192.168.64.2:22", addr, otherKey); err != nil {
+		t.Fatalf("the key of a removed host is not trusted: %v", err)
+	}
+	if err = callback("192.168.64.3:22", otherAddr, key); err == nil {
+		t.Fatal("the key of the host not removed is changed")
+	}
+
+	SkipHostKeyVerify = true
+	defer func() {
+		SkipHostKeyVerify = false
+	}()
+	if err = hostKeyCallback(file)("192.168.64.3:22", otherAddr, key); err != nil {
+		t.Fatalf("the key is verified with SkipHostKeyVerify: %v", err)
+	}
+}
+
+func TestRemoveKnownHosts(t *testing.T) {
+	key := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(newTestHostKey(t))))
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	data := strings.Join([]string{
+		"192.168.64.2 " + key,
+		"[192.168.64.2]:2222 " + key,
+		"192.168.64.20 " + key,
+		"192.168.64.3,192.168.64.4 " + key,
+		"@cert-authority 192.168.64.2 " + key,
+		"# 192.168.64.2",
+	}, "\n") + "\n"
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveKnownHosts(file, "192.168.64.2", "192.168.64.4"); err != nil {
+		t.Fatalf("RemoveKnownHosts() error = %v", err)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "192.168.64.20 " + key + "\n# 192.168.64.2\n"
+	if string(got) != want {
+		t.Errorf("RemoveKnownHosts() = %q, want %q", got, want)
+	}
+	if err = RemoveKnownHosts(filepath.Join(t.TempDir(), "known_hosts"), "192.168.64.2"); err != nil {
+		t.Errorf("RemoveKnownHosts() of a missing file error = %v", err)
+	}
+}
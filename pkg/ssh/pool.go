@@ -0,0 +1,258 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// defaultKeepAliveInterval is the interval of the keepalive requests of the cached connections
+	defaultKeepAliveInterval = 30 * time.Second
+	// defaultMaxSessions is the max number of sessions on a host at the same time, it is less than
+	// the MaxSessions 10 of sshd. A sftp client takes sftpSlots of them.
+	defaultMaxSessions = 8
+	// sftpSlots are the slots of a sftp client, the sync of a sftp client opens a session besides it
+	sftpSlots = 2
+	// aliveTimeout is the timeout of the keepalive request checking a connection after a failure
+	aliveTimeout = 5 * time.Second
+)
+
+// clientPool caches one ssh client per host, the sessions and sftp clients of a host share its connection.
+type clientPool struct {
+	dial        func(host string) (*ssh.Client, error)
+	keepAlive   time.Duration
+	maxSessions int
+
+	lock  sync.Mutex
+	hosts map[string]*hostClient
+}
+
+// hostClient is the cached client of a host, sem bounds the sessions opened on it.
+type hostClient struct {
+	sem chan struct{}
+	// acquire is held while the slots of an open are taken one by one
+	acquire sync.Mutex
+	lock    sync.Mutex
+	client  *ssh.Client
+}
+
+func newClientPool(dial func(host string) (*ssh.Client, error), keepAlive time.Duration, maxSessions int) *clientPool {
+	return &clientPool{
+		dial:        dial,
+		keepAlive:   keepAlive,
+		maxSessions: maxSessions,
+		hosts:       make(map[string]*hostClient),
+	}
+}
+
+func (p *clientPool) host(host string) *hostClient {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	h, ok := p.hosts[host]
+	if !ok {
+		h = &hostClient{sem: make(chan struct{}, p.maxSessions)}
+		p.hosts[host] = h
+	}
+	return h
+}
+
+// open waits for a free slot of the host and calls fn with its client, fn opens a session or a sftp client.
+// When fn fails on a cached client which does not reply to a keepalive request any more, the connection
+// is closed and fn is retried once on a new connection. release frees the slot, it must be called
+// after the session is closed.
+func (p *clientPool) open(host string, fn func(client *ssh.Client) error) (release func(), err error) {
+	return p.openSlots(host, 1, fn)
+}
+
+// openSlots is open taking the slots at once, it is for fn opening more than one channel.
+func (p *clientPool) openSlots(host string, slots int, fn func(client *ssh.Client) error) (release func(), err error) {
+	if slots > p.maxSessions {
+		slots = p.maxSessions
+	}
+	h := p.host(host)
+	// the openers holding a part of their slots would wait for each other forever
+	h.acquire.Lock()
+	for i := 0; i < slots; i++ {
+		h.sem <- struct{}{}
+	}
+	h.acquire.Unlock()
+	release = func() {
+		for i := 0; i < slots; i++ {
+			<-h.sem
+		}
+	}
+	if err = p.call(host, h, fn); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// use is open without a slot, fn opens a channel which is not a session, like a forwarded connection.
+func (p *clientPool) use(host string, fn func(client *ssh.Client) error) error {
+	return p.call(host, p.host(host), fn)
+}
+
+func (p *clientPool) call(host string, h *hostClient, fn func(client *ssh.Client) error) error {
+	client, cached, err := p.get(host, h)
+	if err != nil {
+		return err
+	}
+	if err = fn(client); err != nil && cached && sendKeepAlive(client, aliveTimeout) != nil {
+		logger.Debug("[ssh][%s] connection is broken, reconnecting: %v", host, err)
+		h.evict(client)
+		if client, _, err = p.get(host, h); err == nil {
+			err = fn(client)
+		}
+	}
+	return err
+}
+
+// get returns the cached client of the host, or dials a new one. cached is false for a new client.
+func (p *clientPool) get(host string, h *hostClient) (client *ssh.Client, cached bool, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.client != nil {
+		return h.client, true, nil
+	}
+	client, err = p.dial(host)
+	if err != nil {
+		return nil, false, err
+	}
+	h.client = client
+	go p.keepAliveLoop(host, h, client)
+	return client, false, nil
+}
+
+// keepAliveLoop sends keepalive requests on the client until it is closed, a client without reply is evicted.
+func (p *clientPool) keepAliveLoop(host string, h *hostClient, client *ssh.Client) {
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(p.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			h.evict(client)
+			return
+		case <-ticker.C:
+			if err := sendKeepAlive(client, p.keepAlive); err != nil {
+				logger.Debug("[ssh][%s] keepalive failed: %v", host, err)
+				h.evict(client)
+				return
+			}
+		}
+	}
+}
+
+func sendKeepAlive(client *ssh.Client, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("no reply in %s", timeout)
+	}
+}
+
+// evict closes the client and removes it from the cache if it is still the cached one.
+func (h *hostClient) evict(client *ssh.Client) {
+	h.lock.Lock()
+	if h.client == client {
+		h.client = nil
+	}
+	h.lock.Unlock()
+	_ = client.Close()
+}
+
+// Close closes all the cached clients, the pool dials new ones when it is used again.
+func (p *clientPool) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for host, h := range p.hosts {
+		h.lock.Lock()
+		if h.client != nil {
+			if err := h.client.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				errs = append(errs, fmt.Errorf("[ssh][%s] %v", host, err))
+			}
+			h.client = nil
+		}
+		h.lock.Unlock()
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// open calls fn with the client of the host, the client is from the pool of s, or a new one closed by release
+// when s is not created by NewSSHClient.
+func (s *SSH) open(host string, fn func(client *ssh.Client) error) (release func(), err error) {
+	return s.openSlots(host, 1, fn)
+}
+
+// openSlots is open taking the slots of the host at once.
+func (s *SSH) openSlots(host string, slots int, fn func(client *ssh.Client) error) (release func(), err error) {
+	if s.pool != nil {
+		return s.pool.openSlots(host, slots, fn)
+	}
+	client, err := s.connect(host)
+	if err != nil {
+		return nil, err
+	}
+	if err = fn(client); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return func() { _ = client.Close() }, nil
+}
+
+// use calls fn with the client of the host like open, but the channels opened by fn are not bounded
+// by the max sessions of the host.
+func (s *SSH) use(host string, fn func(client *ssh.Client) error) (release func(), err error) {
+	if s.pool != nil {
+		if err = s.pool.use(host, fn); err != nil {
+			return nil, err
+		}
+		return func() {}, nil
+	}
+	return s.open(host, fn)
+}
+
+// Close closes the connections cached by s, s can still be used and dials new connections.
+func (s *SSH) Close() error {
+	if s.pool == nil {
+		return nil
+	}
+	return s.pool.Close()
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is a ssh server in the same process, it accepts every client and every session.
+type testServer struct {
+	addr  string
+	lock  sync.Mutex
+	conns []net.Conn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	return newTestServerWithConfig(t, &ssh.ServerConfig{NoClientAuth: true})
+}
+
+// newTestServerWithConfig is newTestServer authenticating the clients by the config.
+func newTestServerWithConfig(t *testing.T, config *ssh.ServerConfig) *testServer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AddHostKey(signer)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+	s := &testServer{addr: l.Addr().String()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.conns = append(s.conns, conn)
+			s.lock.Unlock()
+			go s.serve(conn, config)
+		}
+	}()
+	t.Cleanup(s.closeConns)
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for ch := range chans {
+		if ch.ChannelType() == "direct-tcpip" {
+			go forwardTCP(ch)
+			continue
+		}
+		channel, requests, err := ch.Accept()
+		if err != nil {
+			continue
+		}
+		go serveSession(channel, requests)
+	}
+}
+
+// serveSession serves the requests of a session, an exec writes "stdout: <cmd>" to the stdout and "stderr: <cmd>"
+// to the stderr and exits with 0. Like sshd, the stderr is merged into the stdout when the session has a pty.
+func serveSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	pty := false
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			pty = true
+			_ = req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			stderr := channel.Stderr()
+			if pty {
+				stderr = channel
+			}
+			_, _ = fmt.Fprintf(channel, "stdout: %s\n", payload.Command)
+			_, _ = fmt.Fprintf(stderr, "stderr: %s\n", payload.Command)
+			_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// forwardTCP connects the direct-tcpip channel to its target, the server is a jump host.
+func forwardTCP(ch ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(ch.ExtraData(), &payload); err != nil {
+		_ = ch.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = ch.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := ch.Accept()
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		_, _ = io.Copy(channel, conn)
+		_ = channel.CloseWrite()
+	}()
+	_, _ = io.Copy(conn, channel)
+	_ = conn.Close()
+}
+
+// closeConns breaks the connections of all the clients.
+func (s *testServer) closeConns() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func newTestPool(t *testing.T, server *testServer, maxSessions int) (*clientPool, *int32) {
+	var dials int32
+	pool := newClientPool(func(host string) (*ssh.Client, error) {
+		atomic.AddInt32(&dials, 1)
+		return ssh.Dial("tcp", host, &ssh.ClientConfig{
+			User:            "root",
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			Timeout:         5 * time.Second,
+		})
+	}, time.Minute, maxSessions)
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+	return pool, &dials
+}
+
+// openSession opens a session by the pool and closes it at once.
+func openSession(t *testing.T, pool *clientPool, host string) {
+	var session *ssh.Session
+	release, err := pool.open(host, func(client *ssh.Client) (err error) {
+		session, err = client.NewSession()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	_ = session.Close()
+	release()
+}
+
+func Test_clientPool_reuse(t *testing.T) {
+	server := newTestServer(t)
+	pool, dials := newTestPool(t, server, defaultMaxSessions)
+	for i := 0; i < 5; i++ {
+		openSession(t, pool, server.addr)
+	}
+	if got := atomic.LoadInt32(dials); got != 1 {
+		t.Errorf("dials = %d, want 1", got)
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	openSession(t, pool, server.addr)
+	if got := atomic.LoadInt32(dials); got != 2 {
+		t.Errorf("dials after Close = %d, want 2", got)
+	}
+}
+
+func Test_clientPool_reconnect(t *testing.T) {
+	server := newTestServer(t)
+	pool, dials := newTestPool(t, server, defaultMaxSessions)
+	openSession(t, pool, server.addr)
+	server.closeConns()
+	openSession(t, pool, server.addr)
+	if got := atomic.LoadInt32(dials); got != 2 {
+		t.Errorf("dials = %d, want 2", got)
+	}
+}
+
+func Test_clientPool_maxSessions(t *testing.T) {
+	server := newTestServer(t)
+	pool, _ := newTestPool(t, server, 1)
+	release, err := pool.open(server.addr, func(client *ssh.Client) error { return nil })
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	opened := make(chan struct{})
+	go func() {
+		r, err := pool.open(server.addr, func(client *ssh.Client) error { return nil })
+		if err == nil {
+			r()
+		}
+		close(opened)
+	}()
+	select {
+	case <-opened:
+		t.Fatal("open() does not wait for the free slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-opened:
+	case <-time.After(5 * time.Second):
+		t.Fatal("open() is not done after the slot is freed")
+	}
+}
+
+func Test_clientPool_openSlots(t *testing.T) {
+	server := newTestServer(t)
+	pool, _ := newTestPool(t, server, 3)
+	nop := func(client *ssh.Client) error { return nil }
+	release, err := pool.openSlots(server.addr, sftpSlots, nop)
+	if err != nil {
+		t.Fatalf("openSlots() error = %v", err)
+	}
+	// a session fits in the free slot, the next sftp client has to wait
+	r, err := pool.open(server.addr, nop)
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	r()
+	opened := make(chan struct{})
+	go func() {
+		r, err := pool.openSlots(server.addr, sftpSlots, nop)
+		if err == nil {
+			r()
+		}
+		close(opened)
+	}()
+	select {
+	case <-opened:
+		t.Fatal("openSlots() does not wait for the free slots")
+	case <-time.After(100 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-opened:
+	case <-time.After(5 * time.Second):
+		t.Fatal("openSlots() is not done after the slots are freed")
+	}
+	if got := len(pool.host(server.addr).sem); got != 0 {
+		t.Errorf("taken slots = %d, want 0", got)
+	}
+}
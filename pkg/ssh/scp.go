@@ -15,20 +15,16 @@
 package ssh
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
-	"github.com/schollz/progressbar/v3"
-
 	"github.com/labring/sealvm/pkg/utils/file"
-	"github.com/labring/sealvm/pkg/utils/hash"
 	"github.com/labring/sealvm/pkg/utils/iputils"
 	"github.com/labring/sealvm/pkg/utils/logger"
-	"github.com/labring/sealvm/pkg/utils/progress"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
@@ -58,154 +54,152 @@ func (s *SSH) CmdToString(host, cmd, spilt string) (string, error) {
 	return str, fmt.Errorf("command %s %s return nil", host, cmd)
 }
 
-// SftpConnect  is
-func (s *SSH) sftpConnect(host string) (*ssh.Client, *sftp.Client, error) {
-	sshClient, err := s.connect(host)
+// sftpConnect opens a sftp client on the connection of the host, it takes the slot of a session of the sync too.
+// done closes the sftp client and frees it for the other sessions of the host.
+func (s *SSH) sftpConnect(host string) (sshClient *ssh.Client, sftpClient *sftp.Client, done func(), err error) {
+	release, err := s.openSlots(host, sftpSlots, func(client *ssh.Client) error {
+		sshClient = client
+		sftpClient, err = sftp.NewClient(client)
+		return err
+	})
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-
-	// create sftp client
-	sftpClient, err := sftp.NewClient(sshClient)
-	return sshClient, sftpClient, err
+	return sshClient, sftpClient, func() {
+		_ = sftpClient.Close()
+		release()
+	}, nil
 }
 
-// Copy is copy file or dir to remotePath, add md5 validate
+// Copy is copy file or dir to remotePath, only the files whose size or sha256 differ are uploaded
 func (s *SSH) Copy(host, localPath, remotePath string) error {
+	result, err := s.Sync(host, localPath, remotePath, SyncOptions{Progress: "copying files to " + host})
+	if err != nil {
+		return err
+	}
+	logger.Debug("copy %s to %s:%s: %s", localPath, host, remotePath, result)
+	return nil
+}
+
+// Sync copies file or dir to remotePath incrementally, see Sync for the details.
+func (s *SSH) Sync(host, localPath, remotePath string, opts SyncOptions) (*SyncResult, error) {
 	if iputils.IsLocalIP(host, s.LocalAddress) {
 		logger.Debug("local %s copy files src %s to dst %s", host, localPath, remotePath)
-		return file.RecursionCopy(localPath, remotePath)
+		if err := file.RecursionCopy(localPath, remotePath); err != nil {
+			return nil, err
+		}
+		return &SyncResult{Uploaded: []string{""}}, nil
 	}
-	logger.Debug("remote copy files src %s to dst %s", localPath, remotePath)
-	sshClient, sftpClient, err := s.sftpConnect(host)
+	logger.Debug("remote sync files src %s to dst %s", localPath, remotePath)
+	if _, err := os.Stat(localPath); err != nil {
+		return nil, fmt.Errorf("get file stat failed %s", err)
+	}
+	sshClient, sftpClient, done, err := s.sftpConnect(host)
 	if err != nil {
-		return fmt.Errorf("new sftp client failed %s", err)
+		return nil, fmt.Errorf("new sftp client failed %s", err)
 	}
-	defer func() {
-		_ = sftpClient.Close()
-		_ = sshClient.Close()
-	}()
-
-	f, err := os.Stat(localPath)
+	defer done()
+	result, err := Sync(&sftpRemote{ssh: sshClient, sftp: sftpClient}, localPath, remotePath, opts)
 	if err != nil {
-		return fmt.Errorf("get file stat failed %s", err)
+		return nil, fmt.Errorf("[ssh][%s] %v", host, err)
 	}
+	return result, nil
+}
 
-	baseRemoteFilePath := filepath.Dir(remotePath)
-	_, err = sftpClient.ReadDir(baseRemoteFilePath)
-	if err != nil {
-		if err = sftpClient.MkdirAll(baseRemoteFilePath); err != nil {
-			return err
-		}
+// Push applies the changes of the local directory to remotePath, see Push for the details.
+func (s *SSH) Push(host, localPath, remotePath string, changed, deleted []string) (*SyncResult, error) {
+	if iputils.IsLocalIP(host, s.LocalAddress) {
+		logger.Debug("local %s push files src %s to dst %s", host, localPath, remotePath)
+		return pushLocal(localPath, remotePath, changed, deleted)
 	}
-	number := 1
-	if f.IsDir() {
-		number = file.CountDirFiles(localPath)
+	sshClient, sftpClient, done, err := s.sftpConnect(host)
+	if err != nil {
+		return nil, fmt.Errorf("new sftp client failed %s", err)
 	}
-	// no file in dir, do need to send
-	if number == 0 {
-		return nil
+	defer done()
+	result, err := Push(&sftpRemote{ssh: sshClient, sftp: sftpClient}, localPath, remotePath, changed, deleted)
+	if err != nil {
+		return nil, fmt.Errorf("[ssh][%s] %v", host, err)
 	}
-	var (
-		bar = progress.Simple("copying files to "+host, number)
-	)
-	defer func() {
-		_ = bar.Close()
-	}()
+	return result, nil
+}
 
-	if f.IsDir() {
-		s.copyLocalDirToRemote(host, sftpClient, localPath, remotePath, bar)
-	} else {
-		err = s.copyLocalFileToRemote(host, sftpClient, localPath, remotePath)
+// pushLocal is Push when the host is the local host.
+func pushLocal(localPath, remotePath string, changed, deleted []string) (*SyncResult, error) {
+	result := &SyncResult{}
+	for _, rel := range deleted {
+		if err := os.RemoveAll(filepath.Join(remotePath, filepath.FromSlash(rel))); err != nil {
+			return nil, err
+		}
+		result.Deleted = append(result.Deleted, rel)
+	}
+	for _, rel := range changed {
+		src, dst := filepath.Join(localPath, filepath.FromSlash(rel)), filepath.Join(remotePath, filepath.FromSlash(rel))
+		info, err := os.Lstat(src)
 		if err != nil {
-			errMsg := fmt.Sprintf("copy local file to remote failed %v %s %s %s", err, host, localPath, remotePath)
-			logger.Error(errMsg)
+			continue
+		}
+		if info.IsDir() {
+			if err = os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+				return nil, err
+			}
+			continue
 		}
-		_ = bar.Add(1)
+		if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err = file.Copy(src, dst); err != nil {
+			return nil, err
+		}
+		result.Uploaded = append(result.Uploaded, rel)
 	}
-	return nil
+	return result, nil
 }
 
-func (s *SSH) copyLocalDirToRemote(host string, sftpClient *sftp.Client, localPath, remotePath string, epu *progressbar.ProgressBar) {
-	localFiles, err := os.ReadDir(localPath)
+// sftpRemote runs the commands of a sync by sessions and uploads the files by sftp of the same connection,
+// the commands run one by one in the slot taken by sftpConnect.
+type sftpRemote struct {
+	ssh  *ssh.Client
+	sftp *sftp.Client
+}
+
+func (r *sftpRemote) Output(cmd string) ([]byte, error) {
+	session, err := r.ssh.NewSession()
 	if err != nil {
-		logger.Error("read local path dir failed %s %s", host, localPath)
-		return
-	}
-	if err = sftpClient.MkdirAll(remotePath); err != nil {
-		logger.Error("failed to create remote path %s:%v", remotePath, err)
-		return
-	}
-	for _, file := range localFiles {
-		lfp := path.Join(localPath, file.Name())
-		rfp := path.Join(remotePath, file.Name())
-		if file.IsDir() {
-			if err = sftpClient.MkdirAll(rfp); err != nil {
-				logger.Error("failed to create remote path %s:%v", rfp, err)
-				return
-			}
-			s.copyLocalDirToRemote(host, sftpClient, lfp, rfp, epu)
-		} else {
-			err := s.copyLocalFileToRemote(host, sftpClient, lfp, rfp)
-			if err != nil {
-				errMsg := fmt.Sprintf("copy local file to remote failed %v %s %s %s", err, host, lfp, rfp)
-				logger.Error(errMsg)
-				return
-			}
-			_ = epu.Add(1)
-		}
+		return nil, err
 	}
+	defer session.Close()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err = session.Run(cmd); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
 }
 
-// check the remote file existence before copying
-// solve the sesion
-func (s *SSH) copyLocalFileToRemote(host string, sftpClient *sftp.Client, localPath, remotePath string) error {
-	var (
-		srcHash, dstHash string
-	)
-	srcHash = hash.FileDigest(localPath)
-	if s.remoteFileExist(host, remotePath) {
-		dstHash = s.RemoteSha256Sum(host, remotePath)
-		if srcHash == dstHash {
-			logger.Debug("remote dst %s already exists and is the latest version, skip copying process", remotePath)
-			return nil
-		}
-	}
+// Upload writes a temporary file and renames it to the remote path, so a running binary can be replaced.
+func (r *sftpRemote) Upload(localPath, remotePath string, mode os.FileMode, progress io.Writer) error {
 	srcFile, err := os.Open(filepath.Clean(localPath))
 	if err != nil {
 		return err
 	}
 	defer srcFile.Close()
-	dstFile, err := sftpClient.Create(remotePath)
+	tmp := remotePath + ".sealvm-tmp"
+	dstFile, err := r.sftp.Create(tmp)
 	if err != nil {
 		return err
 	}
-	fileStat, err := srcFile.Stat()
-	if err != nil {
-		return fmt.Errorf("get file stat failed %v", err)
-	}
-	// TODO seems not work
-	//if fileStat.Mode()&os.ModeSymlink != 0 {
-	//	target, err := os.Readlink(filepath.Clean(localPath))
-	//	if err != nil {
-	//		return err
-	//	}
-	//	// NOTE: os.Chmod and os.Chtimes don't recoganize symbolic link,
-	//	// which will lead "no such file or directory" error.
-	//	return os.Symlink(target, dest)
-	//}
-	if err := dstFile.Chmod(fileStat.Mode()); err != nil {
-		return fmt.Errorf("chmod remote file failed %v", err)
+	if _, err = io.Copy(io.MultiWriter(dstFile, progress), srcFile); err != nil {
+		_ = dstFile.Close()
+		_ = r.sftp.Remove(tmp)
+		return err
 	}
-	defer dstFile.Close()
-	_, err = io.Copy(dstFile, srcFile)
-	if err != nil {
+	if err = dstFile.Close(); err != nil {
 		return err
 	}
-
-	dstHash = s.RemoteSha256Sum(host, remotePath)
-	if srcHash != dstHash {
-		return fmt.Errorf("[ssh][%s] validate sha256 sum failed %s != %s", host, srcHash, dstHash)
+	if err = r.sftp.Chmod(tmp, mode); err != nil {
+		return fmt.Errorf("chmod remote file failed %v", err)
 	}
-	return nil
+	return r.sftp.PosixRename(tmp, remotePath)
 }
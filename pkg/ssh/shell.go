@@ -0,0 +1,91 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/labring/sealvm/pkg/utils/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+const defaultTerm = "xterm-256color"
+
+// Shell opens an interactive session on the host with the stdin, stdout and stderr of the process,
+// cmd runs instead of the login shell when it is not empty. When the stdin is a terminal, it is in raw mode
+// during the session and the session has a pty which follows the size of the terminal.
+// The error is an *ssh.ExitError when the shell or cmd exits with a non-zero status.
+func (s *SSH) Shell(host, cmd string) error {
+	var session *ssh.Session
+	release, err := s.open(host, func(client *ssh.Client) (err error) {
+		session, err = client.NewSession()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ssh session for %s: %v", host, err)
+	}
+	defer release()
+	defer session.Close()
+	s.requestAgentForwarding(host, session)
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	inFd, outFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if term.IsTerminal(inFd) {
+		state, err := term.MakeRaw(inFd)
+		if err != nil {
+			return fmt.Errorf("failed to set the terminal to raw mode: %v", err)
+		}
+		defer func() {
+			_ = term.Restore(inFd, state)
+		}()
+		width, height, err := term.GetSize(outFd)
+		if err != nil {
+			logger.Debug("failed to get the terminal size: %v", err)
+			width, height = 80, 24
+		}
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = defaultTerm
+		}
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		if err = session.RequestPty(termType, height, width, modes); err != nil {
+			return fmt.Errorf("failed to request pty on %s: %v", host, err)
+		}
+		stop := watchTerminalSize(outFd, func(width, height int) {
+			_ = session.WindowChange(height, width)
+		})
+		defer stop()
+	}
+
+	if cmd == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(cmd)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start the session on %s: %v", host, err)
+	}
+	return session.Wait()
+}
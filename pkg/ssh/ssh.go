@@ -15,13 +15,16 @@
 package ssh
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/labring/sealvm/pkg/configs"
 	"github.com/labring/sealvm/pkg/utils/iputils"
 	"github.com/labring/sealvm/pkg/utils/logger"
 
@@ -33,13 +36,33 @@ type Interface interface {
 	// scp -r /tmp root@192.168.0.2:/root/tmp => Copy("192.168.0.2","tmp","/root/tmp")
 	// need check md5sum
 	Copy(host, srcFilePath, dstFilePath string) error
+	// Download copies the remote file or dir to local path recursively, and returns the number of files copied
+	Download(host, remoteFilePath, localFilePath string) (int, error)
+	// Sync is Copy with options, it returns the files uploaded, skipped and deleted
+	Sync(host, srcFilePath, dstFilePath string, opts SyncOptions) (*SyncResult, error)
+	// Push uploads the changed files of the local dir and removes the deleted ones without comparing them
+	Push(host, srcFilePath, dstFilePath string, changed, deleted []string) (*SyncResult, error)
 	// CmdAsync is exec command on remote host, and asynchronous return logs
 	CmdAsync(host string, cmd ...string) error
 	// Cmd is exec command on remote host, and return combined standard output and standard error
 	Cmd(host, cmd string) ([]byte, error)
+	// CmdStream is exec command on remote host, logs are streamed like CmdAsync and also written to stdout and stderr
+	CmdStream(host, cmd string, stdout, stderr io.Writer) error
 	//CmdToString is exec command on remote host, and return spilt standard output and standard error
 	CmdToString(host, cmd, spilt string) (string, error)
 	Ping(host string) error
+	// IsRemoteDir returns true if the remote path is a directory
+	IsRemoteDir(host, remoteFilePath string) bool
+	// Shell opens an interactive session on the host, it runs cmd instead of the login shell when cmd is not empty
+	Shell(host, cmd string) error
+	// LocalForward forwards the connections of the local address to the remote address through the host
+	// until ctx is done, like ssh -L
+	LocalForward(ctx context.Context, host, localAddr, remoteAddr string) error
+	// RemoteForward forwards the connections of the remote address on the host to the local address
+	// until ctx is done, like ssh -R
+	RemoteForward(ctx context.Context, host, remoteAddr, localAddr string) error
+	// Close closes the connections cached for the hosts
+	Close() error
 }
 
 type SSH struct {
@@ -51,21 +74,54 @@ type SSH struct {
 	PkPassword   string
 	Timeout      *time.Duration
 	LocalAddress *[]net.Addr
+	// KnownHostsFile verifies the host keys, any host key is accepted when it is empty
+	KnownHostsFile string
+	// CertFile is the OpenSSH certificate of PkFile, <PkFile>-cert.pub is used when it is empty
+	CertFile string
+	// AuthMethods are the auth methods in order, see v1.SSH
+	AuthMethods []string
+	// ForwardAgent forwards the ssh agent of SSH_AUTH_SOCK to the hosts
+	ForwardAgent bool
+	// Port is the ssh port of the hosts without port, 22 when it is 0
+	Port int
+	// ProxyJump are the jump hosts to reach the hosts, like the -J of ssh
+	ProxyJump string
+
+	// pool caches a connection per host, a SSH without pool dials a connection for every session
+	pool *clientPool
 }
 
-func NewSSHClient(ssh *v1.SSH, isStdout bool) Interface {
+// NewSSHClient returns the ssh client of the vm, the sessions of a host share one connection
+// until Close is called. The host keys are verified by the known hosts file of the vm.
+func NewSSHClient(vm *v1.VirtualMachine, isStdout bool) Interface {
 	address, err := iputils.ListLocalHostAddrs()
 	// todo: return error?
 	if err != nil {
 		logger.Warn("failed to get local address, %v", err)
 	}
-	return &SSH{
-		isStdout:     isStdout,
-		User:         "root",
-		PkFile:       ssh.PkFile,
-		PkPassword:   ssh.PkPasswd,
-		LocalAddress: address,
+	if vm.Spec.SSH.ProxyJump != "" {
+		// the hosts are behind the jump hosts, none of them is the local host
+		address = &[]net.Addr{}
+	}
+	sshUser := vm.Spec.SSH.User
+	if sshUser == "" {
+		sshUser = "root"
+	}
+	s := &SSH{
+		isStdout:       isStdout,
+		User:           sshUser,
+		PkFile:         vm.Spec.SSH.PkFile,
+		PkPassword:     vm.Spec.SSH.PkPasswd,
+		LocalAddress:   address,
+		KnownHostsFile: configs.KnownHostsFilePath(vm.Name),
+		CertFile:       vm.Spec.SSH.CertFile,
+		AuthMethods:    vm.Spec.SSH.AuthMethods,
+		ForwardAgent:   vm.Spec.SSH.ForwardAgent,
+		Port:           vm.Spec.SSH.Port,
+		ProxyJump:      vm.Spec.SSH.ProxyJump,
 	}
+	s.pool = newClientPool(s.connect, defaultKeepAliveInterval, defaultMaxSessions)
+	return s
 }
 
 type Client struct {
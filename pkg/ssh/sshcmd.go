@@ -33,14 +33,11 @@ func (s *SSH) Ping(host string) error {
 		logger.Debug("ip %s is local ip ,ping is true", host)
 		return nil
 	}
-	client, _, err := s.Connect(host)
+	_, done, err := s.newSession(host)
 	if err != nil {
 		return fmt.Errorf("[ssh %s]create ssh session failed, %v", host, err)
 	}
-	err = client.Close()
-	if err != nil {
-		return err
-	}
+	done()
 	return nil
 }
 
@@ -59,12 +56,11 @@ func (s *SSH) CmdAsync(host string, cmds ...string) error {
 			if isLocal {
 				return exec.Cmd("bash", "-c", cmd)
 			}
-			client, session, err := s.Connect(host)
+			session, done, err := s.newSession(host)
 			if err != nil {
 				return fmt.Errorf("failed to create ssh session for %s: %v", host, err)
 			}
-			defer client.Close()
-			defer session.Close()
+			defer done()
 			stdout, err := session.StdoutPipe()
 			if err != nil {
 				return fmt.Errorf("failed to create stdout pipe for %s: %v", host, err)
@@ -105,6 +101,44 @@ func (s *SSH) CmdAsync(host string, cmds ...string) error {
 	return nil
 }
 
+func (s *SSH) CmdStream(host, cmd string, stdout, stderr io.Writer) error {
+	if iputils.IsLocalIP(host, s.LocalAddress) {
+		logger.Debug("ip %s is local ip ,local ssh cmd exec", host)
+		return exec.CmdWithPrefix(host, stdout, stderr, "bash", "-c", cmd)
+	}
+	session, done, err := s.newSession(host)
+	if err != nil {
+		return fmt.Errorf("failed to create ssh session for %s: %v", host, err)
+	}
+	defer done()
+	outPipe, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe for %s: %v", host, err)
+	}
+	errPipe, err := session.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe for %s: %v", host, err)
+	}
+	if err := session.Start(cmd); err != nil {
+		return fmt.Errorf("failed to start command %s on %s: %v", cmd, host, err)
+	}
+	var lock sync.Mutex
+	doneout := make(chan error, 1)
+	doneerr := make(chan error, 1)
+	go func() {
+		doneerr <- readPipeTo(host, errPipe, stderr, &lock, s.isStdout)
+	}()
+	go func() {
+		doneout <- readPipeTo(host, outPipe, stdout, &lock, s.isStdout)
+	}()
+	<-doneerr
+	<-doneout
+	if err = session.Wait(); err != nil {
+		return fmt.Errorf("failed to execute command on host(%s): %w", host, err)
+	}
+	return nil
+}
+
 func (s *SSH) Cmd(host, cmd string) ([]byte, error) {
 	if iputils.IsLocalIP(host, s.LocalAddress) {
 		logger.Debug("ip is local ip %s default,local ssh cmd exec", host)
@@ -112,12 +146,11 @@ func (s *SSH) Cmd(host, cmd string) ([]byte, error) {
 		return []byte(d), err
 	}
 
-	client, session, err := s.Connect(host)
+	session, done, err := s.newSession(host)
 	if err != nil {
 		return nil, fmt.Errorf("[ssh][%s] create ssh session failed, %s", host, err)
 	}
-	defer client.Close()
-	defer session.Close()
+	defer done()
 	b, err := session.CombinedOutput(cmd)
 	if err != nil {
 		return b, fmt.Errorf("[ssh][%s]run command failed [%s]", host, cmd)
@@ -142,3 +175,26 @@ func readPipe(host string, pipe io.Reader, combineSlice *[]string, combineLock *
 		combineLock.Unlock()
 	}
 }
+
+// readPipeTo copies the lines of pipe to w, a reader has no limit of the line length so a long line
+// like a json output is never split.
+func readPipeTo(host string, pipe io.Reader, w io.Writer, lock *sync.Mutex, isStdout bool) error {
+	r := bufio.NewReader(pipe)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			lock.Lock()
+			if isStdout {
+				fmt.Printf("%s: %s\n", host, line)
+			}
+			lock.Unlock()
+			if w != nil {
+				_, _ = fmt.Fprintln(w, line)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -15,8 +15,12 @@
 package ssh
 
 import (
+	"bytes"
 	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSSH_Cmd(t *testing.T) {
@@ -34,14 +38,10 @@ func TestSSH_Cmd(t *testing.T) {
 			name: "touch test.txt",
 			args: args{
 				ssh: SSH{
-					false,
-					"root",
-					"huaijiahui.com",
-					"",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     false,
+					User:         "root",
+					Password:     "huaijiahui.com",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.56.103",
 				cmd:  "bash /opt/touchTxt.sh",
@@ -53,14 +53,10 @@ func TestSSH_Cmd(t *testing.T) {
 			name: "ls /opt/test",
 			args: args{
 				ssh: SSH{
-					false,
-					"root",
-					"huaijiahui.com",
-					"",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     false,
+					User:         "root",
+					Password:     "huaijiahui.com",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.56.103",
 				cmd:  "ls /opt/test",
@@ -72,14 +68,10 @@ func TestSSH_Cmd(t *testing.T) {
 			name: "remove test.txt",
 			args: args{
 				ssh: SSH{
-					true,
-					"root",
-					"huaijiahui.com",
-					"",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     true,
+					User:         "root",
+					Password:     "huaijiahui.com",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.56.103",
 				cmd:  "bash /opt/removeTxt.sh",
@@ -91,14 +83,10 @@ func TestSSH_Cmd(t *testing.T) {
 			name: "exist 1",
 			args: args{
 				ssh: SSH{
-					true,
-					"root",
-					"huaijiahui.com",
-					"",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     true,
+					User:         "root",
+					Password:     "huaijiahui.com",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.56.103",
 				cmd:  "bash /opt/exit1.sh",
@@ -137,14 +125,10 @@ func TestSSH_CmdAsync(t *testing.T) {
 			name: "touch test.txt",
 			args: args{
 				ssh: SSH{
-					true,
-					"root",
-					"huaijiahui.com",
-					"",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     true,
+					User:         "root",
+					Password:     "huaijiahui.com",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.56.103",
 				cmd:  "bash /opt/touchTxt.sh",
@@ -155,14 +139,10 @@ func TestSSH_CmdAsync(t *testing.T) {
 			name: "ls /root",
 			args: args{
 				ssh: SSH{
-					true,
-					"root",
-					"",
-					"/Users/cuisongliu/.ssh/id_rsa",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     true,
+					User:         "root",
+					PkFile:       "/Users/cuisongliu/.ssh/id_rsa",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.64.30:22",
 				cmd:  "ls /root",
@@ -173,14 +153,10 @@ func TestSSH_CmdAsync(t *testing.T) {
 			name: "remove test.txt",
 			args: args{
 				ssh: SSH{
-					true,
-					"root",
-					"huaijiahui.com",
-					"",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     true,
+					User:         "root",
+					Password:     "huaijiahui.com",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.56.103",
 				cmd:  "bash /opt/removeTxt.sh",
@@ -191,14 +167,10 @@ func TestSSH_CmdAsync(t *testing.T) {
 			name: "exist 1",
 			args: args{
 				ssh: SSH{
-					true,
-					"root",
-					"huaijiahui.com",
-					"",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     true,
+					User:         "root",
+					Password:     "huaijiahui.com",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.56.103",
 				cmd:  "bash /opt/exit1.sh",
@@ -209,14 +181,10 @@ func TestSSH_CmdAsync(t *testing.T) {
 			name: "exist 1",
 			args: args{
 				ssh: SSH{
-					true,
-					"root",
-					"",
-					"/Users/cuisongliu/.ssh/realai",
-					"",
-					"",
-					nil,
-					&[]net.Addr{},
+					isStdout:     true,
+					User:         "root",
+					PkFile:       "/Users/cuisongliu/.ssh/realai",
+					LocalAddress: &[]net.Addr{},
 				},
 				host: "192.168.5.58:2822",
 				cmd:  "ls /root",
@@ -232,3 +200,30 @@ func TestSSH_CmdAsync(t *testing.T) {
 		})
 	}
 }
+
+func Test_readPipeTo_longLine(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+	out := &bytes.Buffer{}
+	var lock sync.Mutex
+	_ = readPipeTo("host", strings.NewReader(long+"\nok\r\ntail"), out, &lock, false)
+	if want := long + "\nok\ntail\n"; out.String() != want {
+		t.Errorf("readPipeTo() wrote %d lines of %d bytes, want 3 lines of %d bytes", strings.Count(out.String(), "\n"), out.Len(), len(want))
+	}
+}
+
+func TestSSH_CmdStream_stderr(t *testing.T) {
+	server := newTestServer(t)
+	s := &SSH{User: "root", LocalAddress: &[]net.Addr{}}
+	s.pool = newClientPool(s.connect, time.Minute, defaultMaxSessions)
+	defer s.Close()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := s.CmdStream(server.addr, "hostname", stdout, stderr); err != nil {
+		t.Fatalf("CmdStream() error = %v", err)
+	}
+	if got := stdout.String(); got != "stdout: hostname\n" {
+		t.Errorf("CmdStream() stdout = %q, want only the stdout", got)
+	}
+	if got := stderr.String(); got != "stderr: hostname\n" {
+		t.Errorf("CmdStream() stderr = %q, want the stderr", got)
+	}
+}
@@ -0,0 +1,440 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labring/sealvm/pkg/utils/hash"
+	"github.com/labring/sealvm/pkg/utils/progress"
+)
+
+// SyncOptions are the options of syncing local files to a host.
+type SyncOptions struct {
+	// Delete removes the remote files which do not exist in the source
+	Delete bool
+	// Ignore skips the paths relative to the source, the ignored remote files are never deleted.
+	// A path in an ignored directory must be ignored too, the remote files are checked one by one
+	Ignore func(rel string, dir bool) bool
+	// Progress is the title of a progress bar over the uploaded bytes, no progress bar is shown when it is empty
+	Progress string
+}
+
+// SyncResult lists the files of a sync by what happened to them, paths are relative to the source.
+type SyncResult struct {
+	Uploaded []string
+	// Chmoded files have the same content but a different mode
+	Chmoded []string
+	Skipped []string
+	Deleted []string
+}
+
+func (r *SyncResult) String() string {
+	return fmt.Sprintf("%d uploaded, %d chmoded, %d skipped, %d deleted", len(r.Uploaded), len(r.Chmoded), len(r.Skipped), len(r.Deleted))
+}
+
+// Remote is the host side of a sync, Output runs a shell command on the host and returns its stdout,
+// the error contains its stderr. Upload writes a local file to the remote path with the mode and the uploaded
+// bytes to progress, the parent directory of the remote path always exists.
+type Remote interface {
+	Output(cmd string) ([]byte, error)
+	Upload(localPath, remotePath string, mode os.FileMode, progress io.Writer) error
+}
+
+// entry is a file or directory of a manifest.
+type entry struct {
+	dir  bool
+	size int64
+	mode os.FileMode
+}
+
+// hashBatch is the max number of files hashed or changed by a single remote command.
+const hashBatch = 100
+
+// Sync copies src to dst on the remote incrementally. A file is uploaded only when it does not exist on the remote
+// or its size or sha256 differs, the modes of files and directories are the same as the local ones.
+// When src is a directory, dst is the directory of its contents, otherwise dst is the path of the file.
+// dst is never removed, it is an error when it exists but is not the same type as src.
+func Sync(r Remote, src, dst string, opts SyncOptions) (*SyncResult, error) {
+	local, err := localManifest(src, opts.Ignore)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.Output(manifestCommand(dst))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", dst, err)
+	}
+	remote, err := parseManifest(string(out))
+	if err != nil {
+		return nil, err
+	}
+	if root, ok := remote[""]; ok && (root.dir != local[""].dir || root.size < 0) {
+		switch {
+		case local[""].dir:
+			return nil, fmt.Errorf("%s exists and is not a directory", dst)
+		case root.dir:
+			return nil, fmt.Errorf("%s is a directory, the target of the file %s must be its own path", dst, src)
+		default:
+			return nil, fmt.Errorf("%s exists and is not a regular file", dst)
+		}
+	}
+	result := &SyncResult{}
+	remotePath := func(rel string) string {
+		return path.Join(dst, rel)
+	}
+
+	removes := make([]string, 0)
+	for rel, e := range local {
+		if re, ok := remote[rel]; ok && (re.dir != e.dir || re.size < 0) {
+			removes = append(removes, rel)
+		}
+	}
+	if opts.Delete {
+		for rel, re := range remote {
+			if opts.Ignore != nil && opts.Ignore(rel, re.dir) {
+				continue
+			}
+			if _, ok := local[rel]; !ok {
+				removes = append(removes, rel)
+			}
+		}
+	}
+	removes = topPaths(removes)
+	for _, rel := range removes {
+		for other := range remote {
+			if other == rel || strings.HasPrefix(other, rel+"/") {
+				delete(remote, other)
+			}
+		}
+		if _, ok := local[rel]; !ok {
+			result.Deleted = append(result.Deleted, rel)
+		}
+	}
+	if err = runBatches(r, "rm -rf --", removes, remotePath); err != nil {
+		return nil, err
+	}
+
+	rels := make([]string, 0, len(local))
+	for rel := range local {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	mkdirs := []string{path.Dir(dst)}
+	if local[""].dir {
+		mkdirs = append(mkdirs, dst)
+	}
+	chmods := make(map[os.FileMode][]string)
+	uploads, candidates := make([]string, 0), make([]string, 0)
+	for _, rel := range rels {
+		e := local[rel]
+		re, ok := remote[rel]
+		if e.dir {
+			if !ok {
+				mkdirs = append(mkdirs, remotePath(rel))
+			}
+			// the mode of an existing dst is kept, it is usually a system directory like /usr/local/bin
+			if !ok || (rel != "" && re.mode != e.mode) {
+				chmods[e.mode] = append(chmods[e.mode], rel)
+			}
+			continue
+		}
+		if !ok || re.size != e.size {
+			uploads = append(uploads, rel)
+			continue
+		}
+		candidates = append(candidates, rel)
+	}
+	if err = runBatches(r, "mkdir -p --", mkdirs, func(p string) string { return p }); err != nil {
+		return nil, err
+	}
+
+	remoteHashes, err := remoteDigests(r, candidates, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range candidates {
+		if remoteHashes[remotePath(rel)] != hash.FileDigest(filepath.Join(src, filepath.FromSlash(rel))) {
+			uploads = append(uploads, rel)
+			continue
+		}
+		if remote[rel].mode != local[rel].mode {
+			chmods[local[rel].mode] = append(chmods[local[rel].mode], rel)
+			result.Chmoded = append(result.Chmoded, rel)
+			continue
+		}
+		result.Skipped = append(result.Skipped, rel)
+	}
+	sort.Strings(uploads)
+	var written io.Writer = io.Discard
+	var size int64
+	for _, rel := range uploads {
+		size += local[rel].size
+	}
+	if opts.Progress != "" && size > 0 {
+		written = progress.Bytes(opts.Progress, size)
+	}
+	for _, rel := range uploads {
+		if err = r.Upload(filepath.Join(src, filepath.FromSlash(rel)), remotePath(rel), local[rel].mode, written); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %v", remotePath(rel), err)
+		}
+		result.Uploaded = append(result.Uploaded, rel)
+	}
+	if err = verifyUploads(r, src, uploads, remotePath); err != nil {
+		return nil, err
+	}
+	modes := make([]os.FileMode, 0, len(chmods))
+	for mode := range chmods {
+		modes = append(modes, mode)
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+	for _, mode := range modes {
+		if err = runBatches(r, fmt.Sprintf("chmod %o --", mode), chmods[mode], remotePath); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(result.Chmoded)
+	sort.Strings(result.Deleted)
+	return result, nil
+}
+
+// Push applies the changes of the local directory src to dst on the remote without comparing them, changed are
+// the files and directories created or modified and deleted are the removed ones, the paths are relative to src.
+// A changed path which does not exist any more is skipped, it is in the deleted ones of the next push.
+func Push(r Remote, src, dst string, changed, deleted []string) (*SyncResult, error) {
+	result := &SyncResult{}
+	remotePath := func(rel string) string {
+		return path.Join(dst, rel)
+	}
+	removes := topPaths(append([]string{}, deleted...))
+	if err := runBatches(r, "rm -rf --", removes, remotePath); err != nil {
+		return nil, err
+	}
+	result.Deleted = removes
+
+	local := make(map[string]entry)
+	parents := make(map[string]bool)
+	for _, rel := range changed {
+		info, err := os.Lstat(filepath.Join(src, filepath.FromSlash(rel)))
+		if err != nil || (!info.IsDir() && !info.Mode().IsRegular()) {
+			continue
+		}
+		local[rel] = entry{dir: info.IsDir(), size: info.Size(), mode: info.Mode().Perm()}
+		if info.IsDir() {
+			parents[rel] = true
+		} else {
+			parents[path.Dir(rel)] = true
+		}
+	}
+	mkdirs := make([]string, 0, len(parents))
+	for rel := range parents {
+		mkdirs = append(mkdirs, remotePath(rel))
+	}
+	sort.Strings(mkdirs)
+	if err := runBatches(r, "mkdir -p --", mkdirs, func(p string) string { return p }); err != nil {
+		return nil, err
+	}
+
+	rels := make([]string, 0, len(local))
+	for rel := range local {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	chmods := make(map[os.FileMode][]string)
+	for _, rel := range rels {
+		e := local[rel]
+		if e.dir {
+			chmods[e.mode] = append(chmods[e.mode], rel)
+			continue
+		}
+		if err := r.Upload(filepath.Join(src, filepath.FromSlash(rel)), remotePath(rel), e.mode, io.Discard); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %v", remotePath(rel), err)
+		}
+		result.Uploaded = append(result.Uploaded, rel)
+	}
+	if err := verifyUploads(r, src, result.Uploaded, remotePath); err != nil {
+		return nil, err
+	}
+	for mode, dirs := range chmods {
+		if err := runBatches(r, fmt.Sprintf("chmod %o --", mode), dirs, remotePath); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// localManifest lists src by the paths relative to it except the ignored ones, src itself is the empty path.
+func localManifest(src string, ignore func(rel string, dir bool) bool) (map[string]entry, error) {
+	manifest := make(map[string]entry)
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = ""
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "" && ignore != nil && ignore(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		manifest[rel] = entry{dir: info.IsDir(), size: info.Size(), mode: info.Mode().Perm()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", src, err)
+	}
+	return manifest, nil
+}
+
+// manifestCommand prints a line of "type size mode path" for dst and everything in it,
+// the type is d for directories, f for regular files and o for the others.
+func manifestCommand(dst string) string {
+	q := shellQuote(dst)
+	return fmt.Sprintf("if [ -d %[1]s ]; then printf 'd 0 %%s \\n' \"$(stat -c %%a %[1]s)\"; "+
+		"cd %[1]s && find . -mindepth 1 \\( -type f -o -type d \\) -printf '%%y %%s %%m %%P\\n'; "+
+		"elif [ -f %[1]s ]; then printf 'f %%s \\n' \"$(stat -c '%%s %%a' %[1]s)\"; "+
+		"elif [ -e %[1]s ]; then echo 'o 0 0 '; fi", q)
+}
+
+func parseManifest(out string) (map[string]entry, error) {
+	manifest := make(map[string]entry)
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid remote file list: %q", line)
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote file list: %q", line)
+		}
+		mode, err := strconv.ParseUint(fields[2], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote file list: %q", line)
+		}
+		switch fields[0] {
+		case "d", "f":
+			manifest[fields[3]] = entry{dir: fields[0] == "d", size: size, mode: os.FileMode(mode).Perm()}
+		default:
+			// the remote path is neither a regular file nor a directory, it is replaced
+			manifest[fields[3]] = entry{size: -1}
+		}
+	}
+	return manifest, nil
+}
+
+// remoteDigests returns the sha256 of the remote files by their remote paths.
+func remoteDigests(r Remote, rels []string, remotePath func(string) string) (map[string]string, error) {
+	digests := make(map[string]string)
+	for i := 0; i < len(rels); i += hashBatch {
+		end := i + hashBatch
+		if end > len(rels) {
+			end = len(rels)
+		}
+		args := make([]string, 0, end-i)
+		for _, rel := range rels[i:end] {
+			args = append(args, shellQuote(remotePath(rel)))
+		}
+		out, err := r.Output("sha256sum -- " + strings.Join(args, " "))
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate remote sha256 sum: %v", err)
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			if sum, p, ok := strings.Cut(strings.TrimRight(line, "\r"), "  "); ok {
+				digests[p] = sum
+			}
+		}
+	}
+	return digests, nil
+}
+
+// verifyUploads checks that the sha256 of the uploaded remote files are the same as the local ones.
+func verifyUploads(r Remote, src string, rels []string, remotePath func(string) string) error {
+	digests, err := remoteDigests(r, rels, remotePath)
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		if want := hash.FileDigest(filepath.Join(src, filepath.FromSlash(rel))); digests[remotePath(rel)] != want {
+			return fmt.Errorf("validate sha256 sum of %s failed %s != %s", remotePath(rel), digests[remotePath(rel)], want)
+		}
+	}
+	return nil
+}
+
+// runBatches runs the command with the remote paths of rels as arguments, at most hashBatch paths a time.
+func runBatches(r Remote, cmd string, rels []string, remotePath func(string) string) error {
+	for i := 0; i < len(rels); i += hashBatch {
+		end := i + hashBatch
+		if end > len(rels) {
+			end = len(rels)
+		}
+		args := make([]string, 0, end-i)
+		for _, rel := range rels[i:end] {
+			args = append(args, shellQuote(remotePath(rel)))
+		}
+		if _, err := r.Output(cmd + " " + strings.Join(args, " ")); err != nil {
+			return fmt.Errorf("failed to run %s: %v", cmd, err)
+		}
+	}
+	return nil
+}
+
+// topPaths removes the paths which are under another path of the list.
+func topPaths(paths []string) []string {
+	sort.Strings(paths)
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if len(out) > 0 {
+			last := out[len(out)-1]
+			if p == last || last == "" || strings.HasPrefix(p, last+"/") {
+				continue
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// shellQuote quotes s by single quotes, nothing in it is expanded by the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// localRemote runs the commands of a sync by the local bash, it needs gnu find and stat.
+// A corrupt remote appends a byte to every uploaded file.
+type localRemote struct {
+	uploads int
+	corrupt bool
+}
+
+func (r *localRemote) Output(cmd string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	c := exec.Command("bash", "-c", cmd)
+	c.Stderr = stderr
+	out, err := c.Output()
+	if err != nil {
+		return out, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func (r *localRemote) Upload(localPath, remotePath string, mode os.FileMode, progress io.Writer) error {
+	r.uploads++
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	if _, err = progress.Write(data); err != nil {
+		return err
+	}
+	if r.corrupt {
+		data = append(data, '\n')
+	}
+	if err = os.WriteFile(remotePath, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(remotePath, mode)
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sync commands need gnu find and stat")
+	}
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "dst")
+	writeFiles(t, src, map[string]string{"a": "a", "bin/sealos": "v1", "etc/b.conf": "b"})
+	if err := os.Chmod(filepath.Join(src, "bin/sealos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r := &localRemote{}
+	result, err := Sync(r, src, dst, SyncOptions{})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if want := []string{"a", "bin/sealos", "etc/b.conf"}; !reflect.DeepEqual(result.Uploaded, want) {
+		t.Errorf("Sync() uploaded = %v, want %v", result.Uploaded, want)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "bin/sealos")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Sync() mode of bin/sealos = %v, %v, want 0755", info.Mode().Perm(), err)
+	}
+
+	// change the content of a file with the same size, the mode of another and add an extraneous remote file
+	writeFiles(t, src, map[string]string{"bin/sealos": "v2"})
+	if err = os.Chmod(filepath.Join(src, "a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dst, map[string]string{"old/c": "c"})
+	result, err = Sync(r, src, dst, SyncOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	want := &SyncResult{Uploaded: []string{"bin/sealos"}, Chmoded: []string{"a"}, Skipped: []string{"etc/b.conf"}, Deleted: []string{"old"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Sync() = %+v, want %+v", result, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "bin/sealos")); string(data) != "v2" {
+		t.Errorf("Sync() content of bin/sealos = %s, want v2", data)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "a")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Sync() mode of a = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+	if _, err = os.Stat(filepath.Join(dst, "old")); !os.IsNotExist(err) {
+		t.Errorf("Sync() old is not deleted: %v", err)
+	}
+
+	uploads := r.uploads
+	if _, err = Sync(r, src, dst, SyncOptions{Delete: true}); err != nil || r.uploads != uploads {
+		t.Errorf("Sync() uploads = %d, %v, want no upload", r.uploads-uploads, err)
+	}
+}
+
+func TestSync_corrupt(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sync commands need gnu find and stat")
+	}
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{"bin/sealos": "v1"})
+	if _, err := Sync(&localRemote{corrupt: true}, src, dst, SyncOptions{Progress: "copying files"}); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Errorf("Sync() of a corrupt upload error = %v, want a sha256 error", err)
+	}
+	if _, err := Push(&localRemote{corrupt: true}, src, dst, []string{"bin/sealos"}, nil); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Errorf("Push() of a corrupt upload error = %v, want a sha256 error", err)
+	}
+}
+
+func TestSync_file(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sync commands need gnu find and stat")
+	}
+	src, dst := filepath.Join(t.TempDir(), "sealos"), filepath.Join(t.TempDir(), "usr/bin/sealos")
+	writeFiles(t, filepath.Dir(src), map[string]string{"sealos": "v1"})
+	r := &localRemote{}
+	for i, want := range []*SyncResult{{Uploaded: []string{""}}, {Skipped: []string{""}}} {
+		result, err := Sync(r, src, dst, SyncOptions{})
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("Sync() %d = %+v, want %+v", i, result, want)
+		}
+	}
+
+	// the target is never removed when it is a directory or the source is a directory and the target is not
+	dir := filepath.Dir(dst)
+	for _, opts := range []SyncOptions{{}, {Delete: true}} {
+		if _, err := Sync(r, src, dir, opts); err == nil {
+			t.Errorf("Sync() of a file to the directory %s with %+v succeeded, want an error", dir, opts)
+		}
+		if _, err := Sync(r, filepath.Dir(src), dst, opts); err == nil {
+			t.Errorf("Sync() of a directory to the file %s with %+v succeeded, want an error", dst, opts)
+		}
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "v1" {
+		t.Errorf("Sync() removed %s: %s, %v", dst, data, err)
+	}
+}
+
+func TestSync_ignore(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sync commands need gnu find and stat")
+	}
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{"main.go": "package main", "_output/sealos": "bin", "a.log": "log"})
+	writeFiles(t, dst, map[string]string{"_output/sealos": "remote", "old": "old"})
+	ignore := func(rel string, dir bool) bool {
+		return rel == "_output" || strings.HasPrefix(rel, "_output/") || strings.HasSuffix(rel, ".log")
+	}
+	result, err := Sync(&localRemote{}, src, dst, SyncOptions{Delete: true, Ignore: ignore})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	want := &SyncResult{Uploaded: []string{"main.go"}, Deleted: []string{"old"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Sync() = %+v, want %+v", result, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "_output/sealos")); string(data) != "remote" {
+		t.Errorf("Sync() changed the ignored remote file, its content is %s", data)
+	}
+}
+
+func TestPush(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sync commands need gnu find and stat")
+	}
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{"pkg/a.go": "a", ".github/ci.yaml": "ci"})
+	writeFiles(t, dst, map[string]string{"pkg/old/b.go": "b", "c.go": "c"})
+	if err := os.MkdirAll(filepath.Join(src, "empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	r := &localRemote{}
+	result, err := Push(r, src, dst, []string{"pkg/a.go", ".github/ci.yaml", "empty", "gone.go"}, []string{"pkg/old", "pkg/old/b.go", "c.go"})
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	want := &SyncResult{Uploaded: []string{".github/ci.yaml", "pkg/a.go"}, Deleted: []string{"c.go", "pkg/old"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Push() = %+v, want %+v", result, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "pkg/a.go")); string(data) != "a" {
+		t.Errorf("Push() content of pkg/a.go = %s, want a", data)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "empty")); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Push() empty dir = %v, %v, want mode 0700", info, err)
+	}
+	for _, rel := range []string{"pkg/old", "c.go"} {
+		if _, err = os.Stat(filepath.Join(dst, rel)); !os.IsNotExist(err) {
+			t.Errorf("Push() %s is not deleted: %v", rel, err)
+		}
+	}
+}
+
+func Test_topPaths(t *testing.T) {
+	got := topPaths([]string{"a/b", "c", "a", "a/b/c", "ab"})
+	if want := []string{"a", "ab", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("topPaths() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"time"
+
+	"golang.org/x/term"
+)
+
+// watchTerminalSize calls resize with the new size of the terminal every time it is resized until stop is called,
+// windows has no signal of resizing so the size is polled.
+func watchTerminalSize(fd int, resize func(width, height int)) (stop func()) {
+	done := make(chan struct{})
+	width, height, _ := term.GetSize(fd)
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w, h, err := term.GetSize(fd)
+				if err == nil && (w != width || h != height) {
+					width, height = w, h
+					resize(width, height)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
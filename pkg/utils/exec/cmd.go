@@ -15,10 +15,14 @@
 package exec
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/labring/sealvm/pkg/utils/logger"
 	strutil "github.com/labring/sealvm/pkg/utils/strings"
@@ -32,6 +36,82 @@ func Cmd(cmd string, args ...string) error {
 	return cmder.Run()
 }
 
+// CmdWithPrefix runs the command and streams its stdout and stderr line by line with the prefix,
+// the lines are also written to stdout and stderr when they are not nil.
+func CmdWithPrefix(prefix string, stdout, stderr io.Writer, cmd string, args ...string) error {
+	return CmdContextWithPrefix(context.Background(), prefix, nil, stdout, stderr, cmd, args...)
+}
+
+// CmdContextWithPrefix is CmdWithPrefix with the environment variables added to the current ones,
+// the command and its children are killed when the context is done.
+func CmdContextWithPrefix(ctx context.Context, prefix string, env []string, stdout, stderr io.Writer, cmd string, args ...string) error {
+	logger.Debug("cmd for pipe in host: ", fmt.Sprintf("%s %s", cmd, strings.Join(args, " ")))
+	cmder := exec.Command(cmd, args...)
+	if len(env) > 0 {
+		cmder.Env = append(os.Environ(), env...)
+	}
+	setProcessGroup(cmder)
+	outPipe, err := cmder.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	errPipe, err := cmder.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmder.Start(); err != nil {
+		return err
+	}
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmder)
+		case <-finished:
+		}
+	}()
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	pipe := func(r io.Reader, w io.Writer) {
+		defer wg.Done()
+		// a reader has no limit of the line length, the pipe is always drained so the command never blocks on it
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+				lock.Lock()
+				fmt.Printf("%s: %s\n", prefix, line)
+				lock.Unlock()
+				if w != nil {
+					_, _ = fmt.Fprintln(w, line)
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	wg.Add(2)
+	go pipe(outPipe, stdout)
+	go pipe(errPipe, stderr)
+	wg.Wait()
+	err = cmder.Wait()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	return err
+}
+
+// CmdOutput runs the command and returns its combined stdout and stderr.
+func CmdOutput(cmd string, args ...string) ([]byte, error) {
+	logger.Debug("cmd for output in host: ", fmt.Sprintf("%s %s", cmd, strings.Join(args, " ")))
+	return exec.Command(cmd, args...).CombinedOutput()
+}
+
 func RunSimpleCmd(cmd string) (string, error) {
 	logger.Debug("cmd for sh in host: ", cmd)
 	// nosemgrep: go.lang.security.audit.dangerous-exec-command.dangerous-exec-command
@@ -0,0 +1,44 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdContextWithPrefix_longLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command needs sh")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stdout := &bytes.Buffer{}
+	// a line longer than the buffer of a scanner, followed by more output than a pipe holds
+	script := "head -c 2097152 /dev/zero | tr '\\0' a; echo; head -c 1048576 /dev/zero | tr '\\0' b; printf '\\ndone'"
+	if err := CmdContextWithPrefix(ctx, "test", nil, stdout, nil, "sh", "-c", script); err != nil {
+		t.Fatalf("CmdContextWithPrefix() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 3 || len(lines[0]) != 2097152 || len(lines[1]) != 1048576 || lines[2] != "done" {
+		t.Errorf("CmdContextWithPrefix() got %d lines, want the long lines and done", len(lines))
+	}
+}
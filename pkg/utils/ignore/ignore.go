@@ -0,0 +1,194 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pattern is a line of the ignore file.
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches the paths by the patterns of .gitignore, the paths are relative to the root
+// of the patterns and separated by slashes.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New returns the matcher of the patterns in the format of .gitignore, the later patterns override the earlier ones.
+func New(lines ...string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, line := range lines {
+		if err := m.add(line); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Load returns the matcher of the .gitignore in the root dir followed by the extra patterns,
+// the .gitignore is optional.
+func Load(root string, extra ...string) (*Matcher, error) {
+	lines := make([]string, 0)
+	f, err := os.Open(filepath.Join(root, ".gitignore"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return New(append(lines, extra...)...)
+}
+
+func (m *Matcher) add(line string) error {
+	line = strings.TrimRight(line, "\r")
+	// the trailing spaces are ignored unless they are escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	p := pattern{}
+	if strings.HasPrefix(line, "!") {
+		p.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+	// a pattern with a slash except the trailing one is relative to the root, otherwise it matches the names at any level
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := translate(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return fmt.Errorf("invalid ignore pattern %s: %v", line, err)
+	}
+	p.re = re
+	m.patterns = append(m.patterns, p)
+	return nil
+}
+
+// translate converts the glob of .gitignore to a regular expression, ** matches any levels of directories.
+func translate(glob string) string {
+	segments := strings.Split(glob, "/")
+	var b strings.Builder
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if seg == "**" {
+			if last {
+				b.WriteString(".*")
+			} else {
+				b.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+		b.WriteString(translateSegment(seg))
+		if !last {
+			b.WriteString("/")
+		}
+	}
+	return b.String()
+}
+
+// translateSegment converts a glob of a path segment, * and ? never match slashes.
+func translateSegment(seg string) string {
+	var b strings.Builder
+	for i := 0; i < len(seg); i++ {
+		c := seg[i]
+		switch c {
+		case '*':
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(seg) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(seg[i])))
+			}
+		case '[':
+			end := strings.IndexByte(seg[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := seg[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Match returns true if the path itself is ignored, the last matching pattern decides.
+// It does not check the parent directories, see Ignored.
+func (m *Matcher) Match(rel string, dir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !dir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// Ignored returns true if the path or any of its parent directories is ignored,
+// a file in an ignored directory can not be included again, the same as git.
+func (m *Matcher) Ignored(rel string, dir bool) bool {
+	if m == nil || rel == "" {
+		return false
+	}
+	segments := strings.Split(rel, "/")
+	for i := 1; i < len(segments); i++ {
+		if m.Match(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.Match(rel, dir)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcher_Ignored(t *testing.T) {
+	m, err := New(
+		"# build outputs",
+		"_output/",
+		"*.log",
+		"!keep.log",
+		"/bin",
+		"docs/**/*.png",
+		"vendor/**",
+		"tmp?",
+		"[ab].txt",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rel  string
+		dir  bool
+		want bool
+	}{
+		{rel: "_output", dir: true, want: true},
+		{rel: "_output/bin/sealos", want: true},
+		{rel: "cmd/_output", dir: true, want: true},
+		{rel: "_output", dir: false, want: false},
+		{rel: "a.log", want: true},
+		{rel: "pkg/a.log", want: true},
+		{rel: "pkg/keep.log", want: false},
+		{rel: "bin", dir: true, want: true},
+		{rel: "cmd/bin", dir: true, want: false},
+		{rel: "docs/a.png", want: true},
+		{rel: "docs/x/y/a.png", want: true},
+		{rel: "pkg/docs/a.png", want: false},
+		{rel: "vendor/github.com/a.go", want: true},
+		{rel: "vendor", dir: true, want: false},
+		{rel: "tmp1", want: true},
+		{rel: "tmp", want: false},
+		{rel: "b.txt", want: true},
+		{rel: "c.txt", want: false},
+		{rel: "main.go", want: false},
+		{rel: "", dir: true, want: false},
+	}
+	for _, tt := range tests {
+		if got := m.Ignored(tt.rel, tt.dir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.rel, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.o\n\n# comment\n!main.o\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(root, ".git/", "main.o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]bool{"a.o": true, "main.o": true, "a.go": false, ".git/HEAD": true} {
+		if got := m.Ignored(rel, false); got != want {
+			t.Errorf("Ignored(%q) = %v, want %v", rel, got, want)
+		}
+	}
+	if _, err = Load(t.TempDir()); err != nil {
+		t.Errorf("Load() without .gitignore error = %v", err)
+	}
+}
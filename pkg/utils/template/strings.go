@@ -0,0 +1,64 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import "encoding/json"
+
+// RenderStrings renders every string of in by render and decodes the result into out. in is converted
+// by json, so the strings of the nested fields, maps and lists are rendered one by one and a rendered
+// value never changes the structure of out.
+func RenderStrings(in, out interface{}, render func(s string) (string, error)) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	var obj interface{}
+	if err = json.Unmarshal(raw, &obj); err != nil {
+		return err
+	}
+	if obj, err = renderStrings(obj, render); err != nil {
+		return err
+	}
+	if raw, err = json.Marshal(obj); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func renderStrings(obj interface{}, render func(s string) (string, error)) (interface{}, error) {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			rendered, err := renderStrings(v, render)
+			if err != nil {
+				return nil, err
+			}
+			o[k] = rendered
+		}
+	case []interface{}:
+		for i, v := range o {
+			rendered, err := renderStrings(v, render)
+			if err != nil {
+				return nil, err
+			}
+			o[i] = rendered
+		}
+	case string:
+		return render(o)
+	}
+	return obj, nil
+}
@@ -29,6 +29,11 @@ func init() {
 		Funcs(funcMap())
 }
 
+// New returns an empty template with the builtin func map.
+func New(name string) *template.Template {
+	return template.New(name).Funcs(funcMap())
+}
+
 func TryParse(text string) (*template.Template, bool, error) {
 	tmp, err := defaultTpl.Parse(text)
 	isFailed := err != nil && err.Error() == "text/template: cannot Parse after Execute"
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Field is a field of a struct by its json name, the fields of inline structs are flattened.
+type Field struct {
+	Name string
+	reflect.StructField
+}
+
+// Fields returns the json fields of the struct type.
+func Fields(t reflect.Type) []Field {
+	fields := make([]Field, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// the fields of unexported embedded structs are still promoted like encoding/json
+		if f.PkgPath != "" && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && (name == "" || strings.Contains(opts, "inline")) {
+			fields = append(fields, Fields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, Field{Name: name, StructField: f})
+	}
+	return fields
+}
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// IsOpaque returns true if the type decodes itself from a json string, like metav1.Duration and metav1.Time.
+func IsOpaque(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(jsonUnmarshaler) || t.Implements(jsonUnmarshaler)
+}
+
+// Documents returns the yaml documents of data, empty documents are skipped.
+func Documents(data []byte) ([]*yamlv3.Node, error) {
+	decoder := yamlv3.NewDecoder(bytes.NewReader(data))
+	docs := make([]*yamlv3.Node, 0)
+	for {
+		doc := &yamlv3.Node{}
+		if err := decoder.Decode(doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Tag == "!!null" {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// UnmarshalNodeStrict decodes the document into obj by the json tags. Unknown fields and values of the wrong type
+// are errors with the line and column of the yaml, scalars of string fields are strings as they are written.
+func UnmarshalNodeStrict(doc *yamlv3.Node, obj interface{}) error {
+	node := doc
+	if node.Kind == yamlv3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if err := checkNode(node, reflect.TypeOf(obj).Elem(), ""); err != nil {
+		return err
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
+
+// UnmarshalStrict decodes the single yaml document of data into obj, see UnmarshalNodeStrict.
+func UnmarshalStrict(data []byte, obj interface{}) error {
+	docs, err := Documents(data)
+	if err != nil {
+		return err
+	}
+	if len(docs) != 1 {
+		return fmt.Errorf("want one yaml document, got %d", len(docs))
+	}
+	return UnmarshalNodeStrict(docs[0], obj)
+}
+
+func nodeError(node *yamlv3.Node, path, format string, args ...interface{}) error {
+	if path == "" {
+		path = "."
+	}
+	return fmt.Errorf("line %d, column %d: %s: %s", node.Line, node.Column, path, fmt.Sprintf(format, args...))
+}
+
+func checkNode(node *yamlv3.Node, t reflect.Type, path string) error {
+	if node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if IsOpaque(t) {
+		if node.Kind != yamlv3.ScalarNode {
+			return nodeError(node, path, "must be a string")
+		}
+		node.Tag = "!!str"
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yamlv3.MappingNode {
+			return nodeError(node, path, "must be an object")
+		}
+		fields := make(map[string]reflect.Type)
+		for _, f := range Fields(t) {
+			fields[f.Name] = f.Type
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				return nodeError(key, path, "unknown field %q", key.Value)
+			}
+			if err := checkNode(value, ft, path+"."+key.Value); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yamlv3.MappingNode {
+			return nodeError(node, path, "must be an object")
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := checkNode(node.Content[i+1], t.Elem(), path+"."+node.Content[i].Value); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if node.Kind != yamlv3.SequenceNode {
+			return nodeError(node, path, "must be a list")
+		}
+		for i, item := range node.Content {
+			if err := checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if node.Kind != yamlv3.ScalarNode {
+			return nodeError(node, path, "must be a string")
+		}
+		// numbers and booleans are kept as they are written, eg: version: 1.20 is "1.20"
+		node.Tag = "!!str"
+	case reflect.Bool:
+		if node.Kind != yamlv3.ScalarNode || node.Tag != "!!bool" {
+			return nodeError(node, path, "must be true or false")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if node.Kind != yamlv3.ScalarNode || node.Tag != "!!int" {
+			return nodeError(node, path, "must be an integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if node.Kind != yamlv3.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			return nodeError(node, path, "must be a number")
+		}
+	}
+	return nil
+}
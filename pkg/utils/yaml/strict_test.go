@@ -0,0 +1,140 @@
+/*
+Copyright 2023 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type strictInner struct {
+	Port  int32            `json:"port,omitempty"`
+	Ratio float64          `json:"ratio,omitempty"`
+	Wait  *metav1.Duration `json:"wait,omitempty"`
+}
+
+type strictEmbedded struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type strictObject struct {
+	Name           string            `json:"name"`
+	Enabled        bool              `json:"enabled,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Inner          *strictInner      `json:"inner,omitempty"`
+	Items          []strictInner     `json:"items,omitempty"`
+	strictEmbedded `json:",inline"`
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    strictObject
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `name: a
+enabled: true
+env: {A: 1.20, B: true}
+inner: {port: 22, ratio: 1, wait: 1m}
+items:
+- port: 1
+tags: [x]
+`,
+			want: strictObject{
+				Name:           "a",
+				Enabled:        true,
+				Env:            map[string]string{"A": "1.20", "B": "true"},
+				Inner:          &strictInner{Port: 22, Ratio: 1, Wait: &metav1.Duration{Duration: time.Minute}},
+				Items:          []strictInner{{Port: 1}},
+				strictEmbedded: strictEmbedded{Tags: []string{"x"}},
+			},
+		},
+		{
+			name:    "unknown field",
+			data:    "name: a\ninner:\n  prot: 22\n",
+			wantErr: `line 3, column 3: .inner: unknown field "prot"`,
+		},
+		{
+			name:    "unknown field in list",
+			data:    "name: a\nitems:\n- port: 1\n- foo: 1\n",
+			wantErr: `line 4, column 3: .items[1]: unknown field "foo"`,
+		},
+		{
+			name:    "list as string",
+			data:    "name: a\nenv:\n  A: [1]\n",
+			wantErr: "line 3, column 6: .env.A: must be a string",
+		},
+		{
+			name:    "string as integer",
+			data:    "name: a\ninner: {port: x}\n",
+			wantErr: "line 2, column 15: .inner.port: must be an integer",
+		},
+		{
+			name:    "scalar as object",
+			data:    "name: a\ninner: x\n",
+			wantErr: "line 2, column 8: .inner: must be an object",
+		},
+		{
+			name:    "invalid duration",
+			data:    "name: a\ninner: {wait: x}\n",
+			wantErr: "invalid duration",
+		},
+		{
+			name:    "multiple documents",
+			data:    "name: a\n---\nname: b\n",
+			wantErr: "want one yaml document, got 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strictObject{}
+			err := UnmarshalStrict([]byte(tt.data), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UnmarshalStrict() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalStrict() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalStrict() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	docs, err := Documents([]byte("---\na: 1\n---\n---\n# comment\n---\nb: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Documents() got %d documents, want 2", len(docs))
+	}
+	if docs[1].Content[0].Line != 7 {
+		t.Errorf("Documents() second document at line %d, want 7", docs[1].Content[0].Line)
+	}
+}
@@ -22,11 +22,15 @@ import (
 type ActionOn struct {
 	Role    string  `json:"role,omitempty"`
 	Indexes []int32 `json:"indexes,omitempty"`
+	// Selector selects hosts by labels, it is combined with Role and Indexes when they are set.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
 type SourceAndTarget struct {
 	Source string `json:"source,omitempty"`
 	Target string `json:"target,omitempty"`
+	// Sync deletes the files in the target which do not exist in the source, only used by copy
+	Sync bool `json:"sync,omitempty"`
 }
 
 type ContentAndTarget struct {
@@ -34,6 +38,83 @@ type ContentAndTarget struct {
 	Target  string `json:"target,omitempty"`
 }
 
+// HTTPProbe checks the status of a http endpoint.
+type HTTPProbe struct {
+	URL string `json:"url"`
+	// Status expected status code, default is 200
+	Status int `json:"status,omitempty"`
+	// Insecure skips the verification of the tls certificate
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// WaitFor waits until the probe succeeds on every selected host, only one probe can be set.
+type WaitFor struct {
+	// Port tcp port to be open, port or host:port
+	Port string `json:"port,omitempty"`
+	// File file to exist
+	File string `json:"file,omitempty"`
+	// Command command to exit 0
+	Command string `json:"command,omitempty"`
+	// HTTP http endpoint to return the status
+	HTTP *HTTPProbe `json:"http,omitempty"`
+	// Match regex which the output of Command or the body of HTTP must match
+	Match string `json:"match,omitempty"`
+	// Timeout of waiting, default is 5m
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Interval between probes, default is 2s
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// OutputMatch are the expectations of an output, all the set ones must match.
+type OutputMatch struct {
+	// Equals the output equals it, leading and trailing spaces are ignored
+	Equals *string `json:"equals,omitempty"`
+	// Contains the output contains it
+	Contains string `json:"contains,omitempty"`
+	// Regex the output matches the regex
+	Regex string `json:"regex,omitempty"`
+	// JSONPath parses the output as json, the other expectations are checked against the result of the jsonpath
+	JSONPath string `json:"jsonPath,omitempty"`
+}
+
+// Assert runs the command on every selected host and checks its result.
+type Assert struct {
+	Command string `json:"command"`
+	// ExitCode expected exit code, default is 0
+	ExitCode *int         `json:"exitCode,omitempty"`
+	Stdout   *OutputMatch `json:"stdout,omitempty"`
+	Stderr   *OutputMatch `json:"stderr,omitempty"`
+	// SameAcrossHosts the checked stdout must be the same on all hosts
+	SameAcrossHosts bool `json:"sameAcrossHosts,omitempty"`
+}
+
+// Template is a go template rendered for every selected host and written to the target.
+type Template struct {
+	// Source path of the local template file
+	Source string `json:"source,omitempty"`
+	// Content inline template, used when Source is empty
+	Content string `json:"content,omitempty"`
+	Target  string `json:"target"`
+	// Mode octal file mode of the target, default is 0644
+	Mode string `json:"mode,omitempty"`
+	// Owner user or user:group of the target
+	Owner string `json:"owner,omitempty"`
+}
+
+// ExecOptions are the options of the exec steps.
+type ExecOptions struct {
+	// Env environment variables of the exec, values are expanded by the shell
+	Env map[string]string `json:"env,omitempty"`
+	// WorkDir working directory of the exec
+	WorkDir string `json:"workdir,omitempty"`
+	// User run the exec as the user by sudo
+	User string `json:"user,omitempty"`
+	// Shell run the exec by the shell, default is bash
+	Shell string `json:"shell,omitempty"`
+	// Timeout kills the exec when it runs longer than it
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
 type ActionData struct {
 	// ActionMount mount src:dst
 	ActionMount *SourceAndTarget `json:"mount,omitempty"`
@@ -45,17 +126,39 @@ type ActionData struct {
 	ActionCopy *SourceAndTarget `json:"copy,omitempty"`
 	// ActionCopyContent copy file content
 	ActionCopyContent *ContentAndTarget `json:"copyContent,omitempty"`
+	// ActionUse use a named action from the action library
+	ActionUse string `json:"use,omitempty"`
+	// ActionWith parameters passed to the action of ActionUse
+	ActionWith map[string]string `json:"with,omitempty"`
+	// ActionWaitFor wait for a port, file, command or http endpoint
+	ActionWaitFor *WaitFor `json:"waitFor,omitempty"`
+	// ActionAssert run a command and check its exit code and output
+	ActionAssert *Assert `json:"assert,omitempty"`
+	// ActionLocal exec cmd on the host running sealvm, the key=value lines written to $SEALVM_OUTPUT become variables
+	ActionLocal string `json:"local,omitempty"`
+	// ActionTemplate render a template with the facts of the host and write it
+	ActionTemplate *Template `json:"template,omitempty"`
+	// Register captures the stdout of the exec or local as the variable of the name
+	Register string `json:"register,omitempty"`
+	// RegisterFormat format of the captured stdout, text or json, default is text
+	RegisterFormat string `json:"registerFormat,omitempty"`
+
+	ExecOptions `json:",inline"`
 }
 
 func (a *ActionData) String() string {
-	return fmt.Sprintf("ActionMount: %v, ActionUmount: %v, ActionExec: %v, ActionCopy: %v, ActionCopyContent: %v",
-		a.ActionMount, a.ActionUmount, a.ActionExec, a.ActionCopy, a.ActionCopyContent)
+	return fmt.Sprintf("ActionMount: %v, ActionUmount: %v, ActionExec: %v, ActionCopy: %v, ActionCopyContent: %v, ActionUse: %v, ActionWith: %v, ActionWaitFor: %v, ActionAssert: %v, ActionLocal: %v, ActionTemplate: %v",
+		a.ActionMount, a.ActionUmount, a.ActionExec, a.ActionCopy, a.ActionCopyContent, a.ActionUse, a.ActionWith, a.ActionWaitFor, a.ActionAssert, a.ActionLocal, a.ActionTemplate)
 }
 
 // ActionSpec defines the desired state of Action
 type ActionSpec struct {
 	Ons  []ActionOn   `json:"ons,omitempty"`
 	Data []ActionData `json:"data,omitempty"`
+	// Env default environment variables of all exec steps
+	Env map[string]string `json:"env,omitempty"`
+	// DependsOn names of the actions in the same file which must complete before this action
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 type ActionPhase string
@@ -64,6 +167,7 @@ const (
 	ActionPhaseFailed    ActionPhase = "Failed"
 	ActionPhaseComplete  ActionPhase = "Complete"
 	ActionPhaseInProcess ActionPhase = "InProcess"
+	ActionPhaseSkipped   ActionPhase = "Skipped"
 )
 
 // ActionStatus defines the observed state of Action
@@ -78,7 +182,8 @@ type ActionStatus struct {
 
 // Action is the Schema for the action API
 type Action struct {
-	metav1.TypeMeta `json:",inline"`
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	Spec   ActionSpec   `json:"spec,omitempty"`
 	Status ActionStatus `json:"status,omitempty"`
@@ -28,12 +28,63 @@ const OrbType = "orb"
 type VirtualMachineSpec struct {
 	Hosts []Host `json:"hosts,omitempty"`
 	SSH   SSH    `json:"ssh"`
+	Hooks Hooks  `json:"hooks,omitempty"`
+	// Forwards are the port forwards of the cluster, they are established by sealvm port-forward --spec
+	Forwards []PortForward `json:"forwards,omitempty"`
 }
 
+// PortForward is a tunnel between a local address and an address reached from a host.
+type PortForward struct {
+	// Host is a host name, a role for its first host or role:index
+	Host string `json:"host"`
+	// Local is the local address like 127.0.0.1:6443
+	Local string `json:"local"`
+	// Remote is the address reached from the host like 127.0.0.1:6443
+	Remote string `json:"remote"`
+	// Reverse listens on the remote address and forwards to the local address, like ssh -R
+	Reverse bool `json:"reverse,omitempty"`
+}
+
+// Hooks are Action files which run automatically in the lifecycle of the vms.
+type Hooks struct {
+	// PostCreate run on all hosts after the vms are created
+	PostCreate []string `json:"postCreate,omitempty"`
+	// PostScaleUp run only on the new hosts after scaling up
+	PostScaleUp []string `json:"postScaleUp,omitempty"`
+	// PreDelete run on the hosts before they are deleted
+	PreDelete []string `json:"preDelete,omitempty"`
+	// PostStart run on the stopped hosts after they are started by the reconcile
+	PostStart []string `json:"postStart,omitempty"`
+}
+
+// The auth methods of SSH.AuthMethods.
+const (
+	// SSHAuthPublicKey authenticates by the private key file and its certificate
+	SSHAuthPublicKey = "publickey"
+	// SSHAuthAgent authenticates by the keys of the ssh agent of SSH_AUTH_SOCK, including the hardware keys
+	SSHAuthAgent = "agent"
+	// SSHAuthPassword authenticates by the password
+	SSHAuthPassword = "password"
+)
+
 type SSH struct {
 	PublicFile string `json:"publicFile,omitempty"`
 	PkFile     string `json:"pkFile,omitempty"`
 	PkPasswd   string `json:"pkPasswd,omitempty"`
+	// CertFile is the OpenSSH certificate of the private key, <pkFile>-cert.pub is used when it is empty
+	CertFile string `json:"certFile,omitempty"`
+	// AuthMethods are the auth methods tried in order, publickey, agent and password by default.
+	// The keys of publickey and agent are offered as one publickey auth of ssh at the position of the first
+	// of them, in their order, so agent keys are tried before password even if password is between them
+	AuthMethods []string `json:"authMethods,omitempty"`
+	// ForwardAgent forwards the ssh agent to the hosts, so the commands on the hosts use the local keys
+	ForwardAgent bool `json:"forwardAgent,omitempty"`
+	// User is the user to login the hosts, root by default
+	User string `json:"user,omitempty"`
+	// Port is the ssh port of the hosts, 22 by default
+	Port int `json:"port,omitempty"`
+	// ProxyJump are the jump hosts to reach the hosts in order, [user@]host[:port] separated by commas like the -J of ssh
+	ProxyJump string `json:"proxyJump,omitempty"`
 }
 
 type Host struct {
@@ -46,6 +97,8 @@ type Host struct {
 	Resources map[string]string `json:"resources,omitempty"`
 	// ecs.t5-lc1m2.large
 	Image string `json:"image,omitempty"`
+	// Labels are applied to every host of the role, actions select hosts by them.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 type Phase string
@@ -96,6 +149,8 @@ type VirtualMachineHostStatus struct {
 	Used      map[string]string `json:"used"`
 	Mounts    map[string]string `json:"mounts,omitempty"`
 	Index     int               `json:"index,omitempty"`
+	// Labels of the current host, they override the labels of the role.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -159,6 +214,20 @@ func (c *VirtualMachine) GetHostStatusByRoleIndex(role string, index int) *Virtu
 	return nil
 }
 
+// GetHostLabels returns the labels of the role merged with the labels of the host.
+func (c *VirtualMachine) GetHostLabels(status *VirtualMachineHostStatus) map[string]string {
+	labels := make(map[string]string)
+	if h := c.GetHostByRole(status.Role); h != nil {
+		for k, v := range h.Labels {
+			labels[k] = v
+		}
+	}
+	for k, v := range status.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
 func (c *VirtualMachine) GetHostStatusByName(name string) *VirtualMachineHostStatus {
 	for _, host := range c.Status.Hosts {
 		if host.ID == name {
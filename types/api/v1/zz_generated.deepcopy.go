@@ -20,6 +20,7 @@
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -27,6 +28,7 @@ import (
 func (in *Action) DeepCopyInto(out *Action) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
@@ -67,6 +69,29 @@ func (in *ActionData) DeepCopyInto(out *ActionData) {
 		*out = new(ContentAndTarget)
 		**out = **in
 	}
+	if in.ActionWith != nil {
+		in, out := &in.ActionWith, &out.ActionWith
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ActionWaitFor != nil {
+		in, out := &in.ActionWaitFor, &out.ActionWaitFor
+		*out = new(WaitFor)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ActionAssert != nil {
+		in, out := &in.ActionAssert, &out.ActionAssert
+		*out = new(Assert)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ActionTemplate != nil {
+		in, out := &in.ActionTemplate, &out.ActionTemplate
+		*out = new(Template)
+		**out = **in
+	}
+	in.ExecOptions.DeepCopyInto(&out.ExecOptions)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionData.
@@ -119,6 +144,11 @@ func (in *ActionOn) DeepCopyInto(out *ActionOn) {
 		*out = make([]int32, len(*in))
 		copy(*out, *in)
 	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionOn.
@@ -148,6 +178,18 @@ func (in *ActionSpec) DeepCopyInto(out *ActionSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionSpec.
@@ -175,6 +217,36 @@ func (in *ActionStatus) DeepCopy() *ActionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Assert) DeepCopyInto(out *Assert) {
+	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int)
+		**out = **in
+	}
+	if in.Stdout != nil {
+		in, out := &in.Stdout, &out.Stdout
+		*out = new(OutputMatch)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Stderr != nil {
+		in, out := &in.Stderr, &out.Stderr
+		*out = new(OutputMatch)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Assert.
+func (in *Assert) DeepCopy() *Assert {
+	if in == nil {
+		return nil
+	}
+	out := new(Assert)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -206,6 +278,83 @@ func (in *ContentAndTarget) DeepCopy() *ContentAndTarget {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecOptions) DeepCopyInto(out *ExecOptions) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecOptions.
+func (in *ExecOptions) DeepCopy() *ExecOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPProbe) DeepCopyInto(out *HTTPProbe) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPProbe.
+func (in *HTTPProbe) DeepCopy() *HTTPProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hooks) DeepCopyInto(out *Hooks) {
+	*out = *in
+	if in.PostCreate != nil {
+		in, out := &in.PostCreate, &out.PostCreate
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostScaleUp != nil {
+		in, out := &in.PostScaleUp, &out.PostScaleUp
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreDelete != nil {
+		in, out := &in.PreDelete, &out.PreDelete
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostStart != nil {
+		in, out := &in.PostStart, &out.PostStart
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hooks.
+func (in *Hooks) DeepCopy() *Hooks {
+	if in == nil {
+		return nil
+	}
+	out := new(Hooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Host) DeepCopyInto(out *Host) {
 	*out = *in
@@ -216,6 +365,13 @@ func (in *Host) DeepCopyInto(out *Host) {
 			(*out)[key] = val
 		}
 	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Host.
@@ -228,9 +384,49 @@ func (in *Host) DeepCopy() *Host {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputMatch) DeepCopyInto(out *OutputMatch) {
+	*out = *in
+	if in.Equals != nil {
+		in, out := &in.Equals, &out.Equals
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputMatch.
+func (in *OutputMatch) DeepCopy() *OutputMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortForward) DeepCopyInto(out *PortForward) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortForward.
+func (in *PortForward) DeepCopy() *PortForward {
+	if in == nil {
+		return nil
+	}
+	out := new(PortForward)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSH) DeepCopyInto(out *SSH) {
 	*out = *in
+	if in.AuthMethods != nil {
+		in, out := &in.AuthMethods, &out.AuthMethods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSH.
@@ -258,6 +454,21 @@ func (in *SourceAndTarget) DeepCopy() *SourceAndTarget {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Template) DeepCopyInto(out *Template) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Template.
+func (in *Template) DeepCopy() *Template {
+	if in == nil {
+		return nil
+	}
+	out := new(Template)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachine) DeepCopyInto(out *VirtualMachine) {
 	*out = *in
@@ -314,6 +525,13 @@ func (in *VirtualMachineHostStatus) DeepCopyInto(out *VirtualMachineHostStatus)
 			(*out)[key] = val
 		}
 	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineHostStatus.
@@ -368,7 +586,13 @@ func (in *VirtualMachineSpec) DeepCopyInto(out *VirtualMachineSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.SSH = in.SSH
+	in.SSH.DeepCopyInto(&out.SSH)
+	in.Hooks.DeepCopyInto(&out.Hooks)
+	if in.Forwards != nil {
+		in, out := &in.Forwards, &out.Forwards
+		*out = make([]PortForward, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineSpec.
@@ -409,3 +633,33 @@ func (in *VirtualMachineStatus) DeepCopy() *VirtualMachineStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WaitFor) DeepCopyInto(out *WaitFor) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPProbe)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WaitFor.
+func (in *WaitFor) DeepCopy() *WaitFor {
+	if in == nil {
+		return nil
+	}
+	out := new(WaitFor)
+	in.DeepCopyInto(out)
+	return out
+}